@@ -0,0 +1,37 @@
+package api
+
+// AsmRequest is the /api/v2/asm request payload
+type AsmRequest struct {
+	Body string
+
+	// Version is the Go version label;
+	// the default version is used if it is empty
+	Version string
+
+	// GOARCH is the target architecture;
+	// the server one is used if it is empty
+	GOARCH string `json:",omitempty"`
+}
+
+// AsmResponse is the /api/v2/asm response payload
+type AsmResponse struct {
+	// Errors contains the build errors, if any
+	Errors    string
+	Functions []*AsmFunction
+}
+
+// AsmFunction is the assembly of a single function
+type AsmFunction struct {
+	Name         string
+	Instructions []*AsmInstruction
+}
+
+// AsmInstruction is a single instruction and the source line
+// it was generated for
+type AsmInstruction struct {
+	// File is the snippet file name (e.g. 'main.go'),
+	// or '<autogenerated>' for generated code
+	File string
+	Line int
+	Text string
+}
@@ -0,0 +1,75 @@
+package api
+
+// CompileRequest is the /api/v2/compile request payload
+type CompileRequest struct {
+	Body string
+
+	// SkipImports tells to only format the code, without running goimports
+	SkipImports bool
+
+	// SkipFormat tells to leave the code as is (this implies SkipImports)
+	SkipFormat bool
+
+	// Vet tells to run go vet on the code before running it
+	Vet bool
+
+	// Test tells to run the code in test mode
+	Test bool
+
+	// Version is the Go version label;
+	// the default version is used if it is empty
+	Version string
+
+	// Stdin, Args and Env are the program standard input,
+	// command-line arguments and additional environment
+	// variables (as KEY=VALUE pairs)
+	Stdin string   `json:",omitempty"`
+	Args  []string `json:",omitempty"`
+	Env   []string `json:",omitempty"`
+
+	// Race, Tags, GOOS and GOARCH are the build options:
+	// the race detector, additional build tags, and the target
+	// platform (programs built for other platforms are not run)
+	Race   bool     `json:",omitempty"`
+	Tags   []string `json:",omitempty"`
+	GOOS   string   `json:",omitempty"`
+	GOARCH string   `json:",omitempty"`
+
+	// Profile tells to collect the CPU and heap profiles of the run
+	Profile bool `json:",omitempty"`
+
+	// Trace tells to record the execution trace of the run
+	Trace bool `json:",omitempty"`
+
+	// Fuzz is the name of the fuzz test to fuzz for a limited time
+	// instead of running the tests (Test must be set)
+	Fuzz string `json:",omitempty"`
+}
+
+// VersionsResponse is the /api/v2/versions response payload
+type VersionsResponse struct {
+	Versions []string
+	Default  string
+}
+
+// FormatResult is the result of the formatting stage
+type FormatResult struct {
+	Error string
+}
+
+// VetResult is the result of the vet stage
+type VetResult struct {
+	Errors string
+}
+
+// CompileResult is the /api/v2/compile response payload;
+// stages that were skipped or not reached have nil results
+type CompileResult struct {
+	// Body is the formatted source code,
+	// if it differs from the submitted one
+	Body    *string
+	Version string
+	Format  *FormatResult
+	Vet     *VetResult
+	Run     *CompileResponse
+}
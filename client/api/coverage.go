@@ -0,0 +1,18 @@
+package api
+
+// Coverage is the test coverage of the snippet
+type Coverage struct {
+	// Percent is the percentage of the statements run by the tests
+	Percent float64
+
+	// Files are the line coverages of the snippet files by file name
+	Files map[string]*FileCoverage
+}
+
+// FileCoverage contains the line numbers of the lines that were run
+// by the tests and of those that weren't; the lines with some code
+// that wasn't run are uncovered
+type FileCoverage struct {
+	Covered   []int
+	Uncovered []int
+}
@@ -0,0 +1,33 @@
+package api
+
+// Optimization diagnostic kinds
+const (
+	EscapeDiagnostic = "escape"
+	MovedDiagnostic  = "moved"
+	InlineDiagnostic = "inline"
+)
+
+// DiagnosticsRequest is the /api/v2/diagnostics request payload
+type DiagnosticsRequest struct {
+	Body string
+
+	// Version is the Go version label;
+	// the default version is used if it is empty
+	Version string
+}
+
+// DiagnosticsResponse is the /api/v2/diagnostics response payload
+type DiagnosticsResponse struct {
+	// Errors contains the build errors, if any
+	Errors      string
+	Diagnostics []*Diagnostic
+}
+
+// Diagnostic is a single optimization decision of the compiler
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Kind    string
+	Message string
+}
@@ -2,6 +2,10 @@ package api
 
 import "time"
 
+// CacheHeader is the /compile response header which is set to "HIT"
+// when the response was served from the server-side cache
+const CacheHeader = "X-Compile-Cache"
+
 // CompileEvent is the individual event structure
 // as returned by play.golang.org
 type CompileEvent struct {
@@ -16,4 +20,55 @@ type CompileResponse struct {
 	Body   *string
 	Events []*CompileEvent
 	Errors string
+
+	// Status is the program exit code
+	Status int
+
+	// IsTest is true if the program was run in test mode,
+	// and TestsFailed is the number of failed tests
+	IsTest      bool
+	TestsFailed int
+
+	// VetErrors contains go vet findings, if vet was run
+	VetErrors string
+	VetOK     bool
+
+	// Tests contains the results of individual tests,
+	// benchmarks and examples in test mode
+	Tests []*TestResult
+
+	// BuildOnly is true if the program was only built
+	// (e.g. for another GOOS/GOARCH), but not run
+	BuildOnly bool
+
+	// Profile contains the CPU and heap profiles of the run,
+	// if requested
+	Profile *Profile
+
+	// Trace contains the execution trace of the run, if requested
+	Trace *Trace
+
+	// Coverage contains the test coverage in test mode
+	Coverage *Coverage
+
+	// Fuzz contains the result of the fuzzing session, if requested
+	Fuzz *FuzzResult
+}
+
+// TestResult is the result of a single test, benchmark or example
+type TestResult struct {
+	// Name is the test name; subtests have names like 'TestFoo/bar'
+	Name string
+
+	// Kind is 'test', 'benchmark', 'example' or 'fuzz'
+	Kind string
+
+	// Status is 'pass', 'fail' or 'skip'
+	Status string
+
+	// Elapsed is the test duration in seconds
+	Elapsed float64
+
+	// Output is the test output
+	Output string
 }
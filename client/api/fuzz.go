@@ -0,0 +1,42 @@
+package api
+
+// FuzzResult is the result of a fuzzing session
+type FuzzResult struct {
+	// Name is the name of the fuzz test
+	Name string
+
+	// Progress is the last reported progress; it is nil if fuzzing
+	// stopped before the first report (e.g. a seed corpus entry failed)
+	Progress *FuzzProgress
+
+	// Crash is the failing input found by the fuzzer, if any
+	Crash *FuzzCrash
+}
+
+// FuzzProgress is the fuzzing progress, which is also
+// streamed as 'fuzz' events during the session
+type FuzzProgress struct {
+	// Elapsed is the fuzzing time in seconds
+	Elapsed int
+
+	// Execs is the number of the fuzz target runs so far,
+	// and ExecsPerSec is the recent rate of the runs
+	Execs       int64
+	ExecsPerSec int64
+
+	// NewInteresting is the number of inputs that expanded
+	// the code coverage during the session; TotalInteresting
+	// counts the seed corpus entries, too
+	NewInteresting   int
+	TotalInteresting int
+}
+
+// FuzzCrash is the failing input found by the fuzzer
+type FuzzCrash struct {
+	// File is the corpus file name, e.g. 'testdata/fuzz/FuzzFoo/582528ddfad69eb5';
+	// adding this file to the snippet makes the input a part of the seed corpus
+	File string
+
+	// Input is the corpus file contents
+	Input string
+}
@@ -0,0 +1,33 @@
+package api
+
+// Profile contains the profiles of the program run
+type Profile struct {
+	CPU  *ProfileData
+	Heap *ProfileData
+}
+
+// ProfileData is a profile of a single kind
+type ProfileData struct {
+	// Unit is the unit of the values, 'nanoseconds' or 'bytes'
+	Unit  string
+	Total int64
+
+	// Frames are the stack frames referred to by the samples
+	Frames  []*ProfileFrame
+	Samples []*ProfileSample
+}
+
+// ProfileFrame is a function call; File is relative to the snippet
+// directory for snippet files (e.g. 'main.go')
+type ProfileFrame struct {
+	Func string
+	File string
+	Line int
+}
+
+// ProfileSample is the total value of the samples with the same stack
+type ProfileSample struct {
+	// Stack contains the Frames indices, the root (e.g. 'main.main') first
+	Stack []int
+	Value int64
+}
@@ -0,0 +1,23 @@
+package api
+
+// SSARequest is the /api/v2/ssa request payload
+type SSARequest struct {
+	Body string
+
+	// Version is the Go version label;
+	// the default version is used if it is empty
+	Version string
+
+	// Func is the GOSSAFUNC value, e.g. 'main.main',
+	// 'util.(*T).Get' (a package name qualifies the function)
+	Func string
+}
+
+// SSAResponse is the /api/v2/ssa response payload
+type SSAResponse struct {
+	// Errors contains the build errors, if any
+	Errors string
+
+	// HTML is the ssa.html page generated by the compiler
+	HTML string
+}
@@ -0,0 +1,43 @@
+package api
+
+import "strings"
+
+// StreamEvent is a single server-sent event
+// of the /api/v2/compile/stream response
+type StreamEvent struct {
+	// Event is 'output' (Data is a CompileEvent), 'result'
+	// (Data is a CompileResult) or 'error' (Data is a string)
+	Event string
+	Data  string
+}
+
+// StreamParser extracts server-sent events from the response text
+// as it grows
+type StreamParser struct {
+	pos int
+}
+
+// Parse returns the events that were completely received
+// since the previous call
+func (p *StreamParser) Parse(text string) []*StreamEvent {
+	var out []*StreamEvent
+	for {
+		i := strings.Index(text[p.pos:], "\n\n")
+		if i == -1 {
+			return out
+		}
+		block := text[p.pos : p.pos+i]
+		p.pos += i + 2
+
+		evt := &StreamEvent{Event: "message"}
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				evt.Event = line[len("event: "):]
+			case strings.HasPrefix(line, "data: "):
+				evt.Data += line[len("data: "):]
+			}
+		}
+		out = append(out, evt)
+	}
+}
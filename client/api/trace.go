@@ -0,0 +1,48 @@
+package api
+
+// Trace is the condensed execution trace of the program run
+type Trace struct {
+	// Duration is the trace duration in nanoseconds
+	Duration int64
+
+	// Goroutines are the goroutines of the program
+	// (the runtime ones are omitted)
+	Goroutines []*TraceGoroutine
+
+	// GC contains the garbage collection phases
+	// and stop-the-world pauses
+	GC []*TraceSpan
+
+	// Truncated is true if the trace is too long,
+	// and its end is omitted
+	Truncated bool
+}
+
+// TraceGoroutine is the timeline of a goroutine
+type TraceGoroutine struct {
+	ID int64
+
+	// Func is the function run by the goroutine (e.g. 'main.main.func1')
+	Func  string
+	Spans []*TraceSpan
+}
+
+// TraceSpan is a time range of the trace; Start and End are
+// nanoseconds since the trace start
+type TraceSpan struct {
+	Start int64
+	End   int64
+
+	// State is 'running', 'runnable', 'waiting' or 'syscall'
+	// for goroutines, and 'gc' or 'stw' for the GC spans
+	State string
+
+	// Reason describes the state, e.g. 'chan receive', 'select',
+	// 'sync' or 'sleep' for waiting goroutines
+	Reason string
+
+	// File and Line are the snippet source line
+	// where the goroutine was blocked
+	File string
+	Line int
+}
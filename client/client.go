@@ -16,6 +16,15 @@ func main() {
 		UseWebfont:       localstorage.GetBool("use-webfont", false),
 		HighlightingMode: localstorage.GetBool("highlighting", true),
 		ShowSidebar:      localstorage.GetBool("show-sidebar", true),
+		Version:          localstorage.Get("go-version", ""),
+		RealTimeReplay:   localstorage.GetBool("realtime-replay", false),
+		HTMLOutput:       localstorage.GetBool("html-output", false),
+		ShowInputs:       localstorage.GetBool("show-inputs", false),
+		Race:             localstorage.GetBool("race", false),
+		BuildTags:        localstorage.Get("build-tags", ""),
+		BuildTarget:      localstorage.Get("build-target", ""),
+		AsmArch:          localstorage.Get("asm-arch", ""),
+		OptDiagnostics:   localstorage.GetBool("opt-diagnostics", false),
 	}
 
 	vecty.RenderBody(a)
@@ -2,9 +2,11 @@ package app
 
 import (
 	"encoding/json"
+	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,13 +20,19 @@ import (
 	"github.com/iafan/syntaxhighlight"
 
 	"github.com/iafan/goplayspace/client/api"
+	"github.com/iafan/goplayspace/client/component/asm"
 	"github.com/iafan/goplayspace/client/component/drawboard"
 	"github.com/iafan/goplayspace/client/component/editor"
 	"github.com/iafan/goplayspace/client/component/editor/undo"
 	"github.com/iafan/goplayspace/client/component/help"
+	"github.com/iafan/goplayspace/client/component/inputs"
 	"github.com/iafan/goplayspace/client/component/log"
+	"github.com/iafan/goplayspace/client/component/profile"
 	"github.com/iafan/goplayspace/client/component/settings"
 	"github.com/iafan/goplayspace/client/component/splitter"
+	"github.com/iafan/goplayspace/client/component/ssa"
+	"github.com/iafan/goplayspace/client/component/tabs"
+	"github.com/iafan/goplayspace/client/component/trace"
 	"github.com/iafan/goplayspace/client/draw"
 	"github.com/iafan/goplayspace/client/hash"
 	"github.com/iafan/goplayspace/client/js/console"
@@ -32,12 +40,21 @@ import (
 	"github.com/iafan/goplayspace/client/js/window"
 	"github.com/iafan/goplayspace/client/ranges"
 	"github.com/iafan/goplayspace/client/util"
+	"github.com/iafan/goplayspace/txtar"
 )
 
 const maxUndoStackSize uint = 50
 
 const idDrawPage = "draw"
 
+// Panes shown in place of the help sidebar
+const (
+	asmPane     = "asm"
+	ssaPane     = "ssa"
+	profilePane = "profile"
+	tracePane   = "trace"
+)
+
 // Application implements the main application view
 type Application struct {
 	vecty.Core
@@ -49,6 +66,20 @@ type Application struct {
 	Topic   string
 	Imports map[string]string
 
+	// Snippet files; Input holds the text of the active one
+	files      []*snippetFile
+	activeFile int
+
+	// Program inputs; they are saved with shared snippets
+	inputs *programInputs
+
+	// Function declarations of the active file
+	funcDecls []*funcDecl
+
+	// Test, benchmark and example functions of the snippet
+	testFuncs   map[string]*testFunc
+	hasMainFunc bool
+
 	// Settings
 	Theme            string
 	TabWidth         int
@@ -56,6 +87,18 @@ type Application struct {
 	UseWebfont       bool
 	HighlightingMode bool
 	ShowSidebar      bool
+	Version          string
+	RealTimeReplay   bool
+	HTMLOutput       bool
+	ShowInputs       bool
+	Race             bool
+	BuildTags        string
+	BuildTarget      string
+	AsmArch          string
+	OptDiagnostics   bool
+
+	// Go versions provided by the server
+	versions []string
 
 	Hash      *hash.Hash
 	snippetID string
@@ -63,17 +106,66 @@ type Application struct {
 	modifierKey          string
 	isLoading            bool
 	isCompiling          bool
+	isCancelled          bool
 	isSharing            bool
 	isDrawingMode        bool
 	hasCompilationErrors bool
 	needRender           bool
 	showSettings         bool
 	showDrawHelp         bool
+	sidePane             string
+
+	// runRequest is the request of the running program
+	runRequest *xhr.Request
 
 	// Log properties
-	hasRun bool
-	err    string
-	events []*api.CompileEvent
+	hasRun      bool
+	isCached    bool
+	err         string
+	events      []*api.CompileEvent
+	status      int
+	isTest      bool
+	testsFailed int
+	vetErrors   string
+	tests       []*api.TestResult
+	runVersion  string
+	buildTarget string
+	replay      *log.Replay
+
+	// Assembly pane properties; asmSeq identifies
+	// the latest request
+	isLoadingAsm bool
+	asmSeq       int
+	asmFunctions []*api.AsmFunction
+	asmError     string
+
+	// Profile properties; profileRun is true if the current run
+	// collects the profile
+	profileRun  bool
+	profile     *api.Profile
+	profileKind string
+
+	// Trace properties; traceRun is true if the current run
+	// records the trace, and traced is true if the last one did
+	traceRun bool
+	traced   bool
+	trace    *api.Trace
+
+	// coverage is the test coverage of the last run in test mode
+	coverage *api.Coverage
+
+	// Fuzzing properties; fuzzTarget is the fuzz test fuzzed
+	// by the current run, and fuzzProgress is its latest progress
+	fuzzTarget   string
+	fuzzProgress *api.FuzzProgress
+	fuzz         *api.FuzzResult
+
+	// SSA pane properties
+	isLoadingSSA bool
+	ssaSeq       int
+	ssaFunc      string
+	ssaHTML      string
+	ssaError     string
 
 	// Draw mode properties
 	actions draw.ActionList
@@ -125,7 +217,6 @@ func (a *Application) onEditorKeyDown(e *vecty.Event) {
 	}
 }
 
-var compileErrorLineExtractorR = regexp.MustCompile(`\/main\.go:(\d+):\s`)
 var fmtErrorLineExtractorR = regexp.MustCompile(`(?m)^(\d+):(\d+):\s`)
 
 var domMonitorInterval = 5 * time.Millisecond
@@ -146,42 +237,197 @@ func (a *Application) doRun() {
 	a.isCompiling = true
 	//a.doFormat()
 	go a.doRunAsync()
+	switch a.sidePane {
+	case asmPane:
+		a.doLoadAsm()
+	case ssaPane:
+		a.doLoadSSA()
+	}
 }
 
 func (a *Application) doRunAsync() {
 	defer a.doRunAsyncComplete()
 
 	a.hasRun = true
-
-	bodyBytes, err := xhr.Send("POST", "/compile", []byte(a.Input))
+	a.isCached = false
+	a.isCancelled = false
+	if a.replay != nil {
+		a.replay.Stop()
+		a.replay = nil
+	}
+	a.events = nil
+	a.status = 0
+	a.isTest = false
+	a.testsFailed = 0
+	a.tests = nil
+	a.vetErrors = ""
+	a.runVersion = ""
+	a.buildTarget = ""
+	a.profile = nil
+	a.setProfileLines()
+	a.trace = nil
+	a.traced = false
+	a.setCoverage(nil)
+	a.fuzzProgress = nil
+	a.fuzz = nil
+
+	goos, goarch := splitBuildTarget(a.BuildTarget)
+	reqBytes, err := json.Marshal(&api.CompileRequest{
+		Body:    a.getSource(),
+		Vet:     true,
+		Test:    a.isTestMode(),
+		Version: a.Version,
+		Stdin:   a.inputs.stdin,
+		Args:    parseArgs(a.inputs.args),
+		Env:     parseEnv(a.inputs.env),
+		Race:    a.Race,
+		Tags:    parseBuildTags(a.BuildTags),
+		GOOS:    goos,
+		GOARCH:  goarch,
+		Profile: a.profileRun,
+		Trace:   a.traceRun,
+		Fuzz:    a.fuzzTarget,
+	})
 	if err != nil {
 		a.err = err.Error()
 		return
 	}
 
-	compileResponse := api.CompileResponse{}
+	// the program output is streamed as server-sent events,
+	// which are handled as the response text grows
+
+	var result *api.CompileResult
+	var streamErr string
+	parser := &api.StreamParser{}
+
+	req := xhr.NewRequest("POST", "/api/v2/compile/stream")
+	req.SetRequestHeader("Content-Type", "application/json")
+	onProgress := func() {
+		if req.Get("status").Int() != 200 {
+			return
+		}
+		for _, evt := range parser.Parse(req.Get("responseText").String()) {
+			switch evt.Event {
+			case "output":
+				compileEvent := &api.CompileEvent{}
+				if err := json.Unmarshal([]byte(evt.Data), compileEvent); err == nil {
+					a.events = append(a.events, compileEvent)
+					a.wantRerender("onProgress")
+					util.Schedule(func() { a.log.ScrollToBottom() })
+				}
+			case "fuzz":
+				progress := &api.FuzzProgress{}
+				if err := json.Unmarshal([]byte(evt.Data), progress); err == nil {
+					a.fuzzProgress = progress
+					a.wantRerender("onProgress")
+				}
+			case "result":
+				result = &api.CompileResult{}
+				if err := json.Unmarshal([]byte(evt.Data), result); err != nil {
+					streamErr = err.Error()
+				}
+			case "error":
+				json.Unmarshal([]byte(evt.Data), &streamErr)
+			}
+		}
+	}
+	req.Call("addEventListener", "progress", onProgress)
 
-	err = json.Unmarshal(bodyBytes, &compileResponse)
+	a.runRequest = req
+	err = req.Send(string(reqBytes))
+	a.runRequest = nil
+	if err == xhr.ErrAborted {
+		a.isCancelled = true
+		return
+	}
 	if err != nil {
 		a.err = err.Error()
 		return
 	}
+	if req.Status != 200 {
+		a.hasRun = false
+		a.err = strings.TrimSpace(req.ResponseText)
+		return
+	}
+
+	a.isCached = req.ResponseHeader(api.CacheHeader) == "HIT"
+
+	// handle the events received after the last progress event
+	onProgress()
+
+	if streamErr != "" {
+		a.hasRun = false
+		a.err = streamErr
+		return
+	}
+	if result == nil {
+		a.hasRun = false
+		a.err = "Incomplete response from the server"
+		return
+	}
+
+	// update the source code first, since this resets the error state
+	if result.Body != nil {
+		a.setSource(*result.Body)
+	}
+
+	a.runVersion = result.Version
+
+	if result.Format != nil && result.Format.Error != "" {
+		a.hasRun = false
+		a.err = result.Format.Error
+		a.hasCompilationErrors = true
+		a.setCompileErrors(a.err)
+		return
+	}
+
+	if result.Vet != nil {
+		a.vetErrors = result.Vet.Errors
+	}
+
+	compileResponse := result.Run
+	if compileResponse == nil {
+		return
+	}
+
+	// output that wasn't streamed live (e.g. from play.golang.org
+	// or the cache) can be replayed with the original delays
+	streamed := len(a.events) > 0
 
 	a.err = compileResponse.Errors
 	a.events = compileResponse.Events
 	a.hasCompilationErrors = a.err != ""
 
-	if compileResponse.Body != nil {
-		a.setEditorText(*compileResponse.Body)
+	if a.RealTimeReplay && !streamed && len(a.events) > 0 {
+		a.replay = log.NewReplay(a.events, a.onReplayChange)
+		a.replay.Start()
+	}
+	a.status = compileResponse.Status
+	a.isTest = compileResponse.IsTest
+	a.testsFailed = compileResponse.TestsFailed
+	a.tests = compileResponse.Tests
+	if compileResponse.BuildOnly {
+		a.buildTarget = a.BuildTarget
 	}
+	a.profile = compileResponse.Profile
+	a.setProfileLines()
+	a.trace = compileResponse.Trace
+	a.traced = a.traceRun && !a.hasCompilationErrors
+	a.setCoverage(compileResponse.Coverage)
+	a.fuzz = compileResponse.Fuzz
 
 	// extract line numbers from compilation error message
 
-	if matches := compileErrorLineExtractorR.FindAllStringSubmatch(compileResponse.Errors, -1); matches != nil {
-		a.errorLines = make(map[string]bool)
-		for _, m := range matches {
-			a.errorLines[m[1]] = true
-		}
+	a.setCompileErrors(compileResponse.Errors)
+
+	// extract line numbers from data race reports
+
+	a.addRaceErrors(a.events)
+
+	// extract line numbers from vet findings
+
+	if lines := a.extractFileLines(a.vetErrors)[a.activeFile]; lines != nil {
+		a.warningLines = lines
 	}
 
 	// parse gopher commands
@@ -195,8 +441,33 @@ func (a *Application) doRunAsync() {
 	}
 }
 
+func (a *Application) stopButtonClick(e *vecty.Event) {
+	a.doStop()
+}
+
+// doStop aborts the running request; the server notices
+// the client disconnecting, and stops the program
+func (a *Application) doStop() {
+	if a.runRequest != nil {
+		a.runRequest.Abort()
+	}
+}
+
+func (a *Application) onReplayChange() {
+	a.wantRerender("onReplayChange")
+	util.Schedule(func() { a.log.ScrollToBottom() })
+}
+
 func (a *Application) doRunAsyncComplete() {
 	a.isCompiling = false
+	a.profileRun = false
+	a.traceRun = false
+	a.fuzzTarget = ""
+	// the diagnostics are requested after the run,
+	// since the server may have reformatted the code
+	if a.OptDiagnostics && !a.isCancelled {
+		go a.doLoadDiagnosticsAsync()
+	}
 	a.wantRerender("doRunAsyncComplete")
 	util.Schedule(func() { a.log.ScrollToBottom() })
 }
@@ -214,13 +485,18 @@ func (a *Application) doShare() {
 func (a *Application) doShareAsync() {
 	defer a.doShareAsyncComplete()
 
-	bodyBytes, err := xhr.Send("POST", "/share", []byte(a.Input))
+	req := xhr.NewRequest("POST", "/share")
+	err := req.Send(joinInputs(a.getSource(), a.inputs))
 	if err != nil {
 		a.err = err.Error()
 		return
 	}
+	if req.Status != 200 {
+		a.err = strings.TrimSpace(req.ResponseText)
+		return
+	}
 
-	a.snippetID = string(bodyBytes) // already 'loaded'
+	a.snippetID = req.ResponseText // already 'loaded'
 	a.Hash.SetID(a.snippetID)
 }
 
@@ -273,7 +549,12 @@ func (a *Application) doLoadAsync(id string) {
 		return
 	}
 
-	a.setEditorText(req.ResponseText)
+	src, in := splitInputs(req.ResponseText)
+	a.inputs = in
+	if !in.isEmpty() {
+		a.ShowInputs = true
+	}
+	a.setSource(src)
 	// setting new text will cause OnChange event,
 	// and hash will be reset; so update it afterwards
 	a.Hash.ID = id
@@ -290,12 +571,12 @@ func (a *Application) formatButtonClick(e *vecty.Event) {
 }
 
 func (a *Application) format(text string) (string, error) {
-	if a.Input == "" {
-		return "", nil
+	if text == "" || path.Ext(a.activeFileName()) != ".go" {
+		return text, nil
 	}
 
 	//console.Time("format")
-	bytes, err := format.Source([]byte(a.Input))
+	bytes, err := format.Source([]byte(text))
 	//console.TimeEnd("format")
 
 	if err != nil {
@@ -354,55 +635,101 @@ func (a *Application) parseAndReportErrors(text string) {
 	a.errorLines = nil
 	a.hasCompilationErrors = false
 
-	if text == "" {
+	if text == "" && a.activeFileName() == txtar.MainFile {
 		a.setEditorState(blankTemplate, blankTemplatePos, blankTemplatePos)
 	}
 
-	// parse source code to get list of imports and parsing error, if any;
-	// note that we don't clear the list of imports since we want to
-	// keep the previously known good mapping even if there are parsing errors
+	a.files[a.activeFile].text = a.Input
 
-	fset := token.NewFileSet()
-	//console.Time("parse")
-	f, err := parser.ParseFile(fset, "", a.Input, parser.AllErrors)
-	//console.TimeEnd("parse")
+	// parse each .go file separately to get parsing errors, if any,
+	// the list of imports of the active file, and the test functions
 
-	a.Imports = make(map[string]string)
-	if f != nil {
-		for _, imp := range f.Imports {
-			var name string
-			path := strings.Trim(imp.Path.Value, `"`)
-			if imp.Name != nil {
-				name = imp.Name.Name
-			} else {
-				name = path
-				if i := strings.LastIndex(path, "/"); i >= -1 {
-					name = path[i+1:]
-				}
-			}
+	a.testFuncs = make(map[string]*testFunc)
+	a.hasMainFunc = false
+	a.funcDecls = nil
 
-			// FIXME: should we somehow deal with '.' and '_' import names?
+	var errs []string
+	for i, sf := range a.files {
+		// compilation errors and diagnostics are no longer valid
+		// once the file is edited
+		if i == a.activeFile {
+			sf.errorLines = nil
+			sf.annotations = nil
+			sf.profileLines = nil
+			sf.coverageLines = nil
+		}
+		sf.hasErrors = sf.errorLines != nil
 
-			if name != "." && name != "_" {
-				a.Imports[name] = path // short package name
-			}
-			if path != "." && path != "_" && path != name {
-				a.Imports[path] = path // full package name
+		if path.Ext(sf.name) != ".go" {
+			continue
+		}
+
+		fset := token.NewFileSet()
+		//console.Time("parse")
+		f, err := parser.ParseFile(fset, "", sf.text, parser.AllErrors)
+		//console.TimeEnd("parse")
+
+		if i == a.activeFile {
+			a.updateImports(f)
+			a.collectFuncDecls(fset, f)
+		}
+		a.collectTestFuncs(i, fset, f)
+
+		if err == nil {
+			continue
+		}
+
+		sf.hasErrors = true
+		msg := err.Error()
+
+		// extract line numbers from parser error message
+
+		if i == a.activeFile {
+			if matches := fmtErrorLineExtractorR.FindAllStringSubmatch(msg, -1); matches != nil {
+				a.warningLines = make(map[string]bool)
+				for _, m := range matches {
+					a.warningLines[m[1]] = true
+				}
 			}
 		}
+
+		if len(a.files) > 1 {
+			msg = sf.name + ": " + msg
+		}
+		errs = append(errs, msg)
 	}
 
-	if err != nil {
-		a.err = err.Error()
+	a.err = strings.Join(errs, "\n")
+}
 
-		// extract line numbers from parser error message
+// updateImports updates the list of imports from the parsed file;
+// note that the file may be partially parsed if it has errors
+func (a *Application) updateImports(f *ast.File) {
+	a.Imports = make(map[string]string)
+	if f == nil {
+		return
+	}
 
-		if matches := fmtErrorLineExtractorR.FindAllStringSubmatch(a.err, -1); matches != nil {
-			a.warningLines = make(map[string]bool)
-			for _, m := range matches {
-				a.warningLines[m[1]] = true
+	for _, imp := range f.Imports {
+		var name string
+		path := strings.Trim(imp.Path.Value, `"`)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		} else {
+			name = path
+			if i := strings.LastIndex(path, "/"); i >= -1 {
+				name = path[i+1:]
 			}
 		}
+
+		// FIXME: should we somehow deal with '.' and '_' import names?
+
+		if name != "." && name != "_" {
+			a.Imports[name] = path // short package name
+		}
+		if path != "." && path != "_" && path != name {
+			a.Imports[path] = path // full package name
+		}
 	}
 }
 
@@ -484,12 +811,126 @@ func (a *Application) updateHighlighting(on bool) {
 	a.wantRerender("updateHighlighting")
 }
 
+func (a *Application) updateRealTimeReplay(val bool) {
+	a.RealTimeReplay = val
+	localstorage.Set("realtime-replay", val)
+	a.wantRerender("updateRealTimeReplay")
+}
+
+func (a *Application) updateHTMLOutput(val bool) {
+	a.HTMLOutput = val
+	localstorage.Set("html-output", val)
+	a.wantRerender("updateHTMLOutput")
+}
+
+func (a *Application) updateVersion(val string) {
+	a.Version = val
+	localstorage.Set("go-version", val)
+	a.wantRerender("updateVersion")
+}
+
+func (a *Application) versionChange(e *vecty.Event) {
+	a.updateVersion(e.Target.Get("value").String())
+}
+
+func (a *Application) doLoadVersionsAsync() {
+	defer a.wantRerender("doLoadVersionsAsync")
+
+	req := xhr.NewRequest("GET", "/api/v2/versions")
+	err := req.Send(nil)
+	if err != nil || req.Status != 200 {
+		console.Log("Failed to load the list of Go versions")
+		return
+	}
+
+	versionsResponse := api.VersionsResponse{}
+	err = json.Unmarshal([]byte(req.ResponseText), &versionsResponse)
+	if err != nil {
+		console.Log("Failed to decode the list of Go versions:", err.Error())
+		return
+	}
+
+	a.versions = versionsResponse.Versions
+
+	// the previously selected version may be no longer available
+	for _, v := range a.versions {
+		if v == a.Version {
+			return
+		}
+	}
+	a.Version = versionsResponse.Default
+}
+
+// getVersionSelector renders the Go version dropdown;
+// it is only shown if the server provides several versions
+func (a *Application) getVersionSelector() vecty.MarkupOrChild {
+	if len(a.versions) < 2 {
+		return nil
+	}
+
+	items := make([]vecty.MarkupOrChild, 0, len(a.versions)+1)
+	items = append(items, vecty.Markup(
+		vecty.Class("version"),
+		vecty.Attribute("title", "Go version"),
+		event.Change(a.versionChange),
+	))
+	for _, v := range a.versions {
+		items = append(items, elem.Option(
+			vecty.Markup(
+				vecty.Property("value", v),
+				vecty.Property("selected", v == a.Version),
+			),
+			vecty.Text(v),
+		))
+	}
+	return elem.Select(items...)
+}
+
 func (a *Application) updateShowSidebar(val bool) {
 	a.ShowSidebar = val
 	localstorage.Set("show-sidebar", val)
 	a.wantRerender("updateShowSidebar")
 }
 
+func (a *Application) updateRace(val bool) {
+	a.Race = val
+	localstorage.Set("race", val)
+	a.wantRerender("updateRace")
+}
+
+func (a *Application) updateBuildTags(val string) {
+	a.BuildTags = val
+	localstorage.Set("build-tags", val)
+	a.wantRerender("updateBuildTags")
+}
+
+func (a *Application) updateBuildTarget(val string) {
+	a.BuildTarget = val
+	localstorage.Set("build-target", val)
+	a.wantRerender("updateBuildTarget")
+}
+
+func (a *Application) updateShowInputs(val bool) {
+	a.ShowInputs = val
+	localstorage.Set("show-inputs", val)
+	a.wantRerender("updateShowInputs")
+}
+
+func (a *Application) inputsButtonClick(e *vecty.Event) {
+	a.updateShowInputs(!a.ShowInputs)
+}
+
+func (a *Application) onInputsChange(p *inputs.Panel) {
+	a.inputs = &programInputs{
+		stdin: p.Stdin,
+		args:  p.Args,
+		env:   p.Env,
+	}
+	// the inputs are a part of the shared snippet
+	a.Hash.Reset()
+	a.wantRerender("onInputsChange")
+}
+
 func (a *Application) onSettingsChange(d *settings.Dialog) {
 	if d.Theme != a.Theme {
 		a.updateTheme(d.Theme)
@@ -514,6 +955,30 @@ func (a *Application) onSettingsChange(d *settings.Dialog) {
 	if d.ShowSidebar != a.ShowSidebar {
 		a.updateShowSidebar(d.ShowSidebar)
 	}
+
+	if d.RealTimeReplay != a.RealTimeReplay {
+		a.updateRealTimeReplay(d.RealTimeReplay)
+	}
+
+	if d.HTMLOutput != a.HTMLOutput {
+		a.updateHTMLOutput(d.HTMLOutput)
+	}
+
+	if d.Race != a.Race {
+		a.updateRace(d.Race)
+	}
+
+	if d.BuildTags != a.BuildTags {
+		a.updateBuildTags(d.BuildTags)
+	}
+
+	if d.BuildTarget != a.BuildTarget {
+		a.updateBuildTarget(d.BuildTarget)
+	}
+
+	if d.OptDiagnostics != a.OptDiagnostics {
+		a.updateOptDiagnostics(d.OptDiagnostics)
+	}
 }
 
 func (a *Application) formatShortcutPressed(e interface{}) {
@@ -533,6 +998,7 @@ func (a *Application) Mount() {
 		a.onHashChange(a.Hash)
 	}
 	window.AddEventListener("resize", a.onResize)
+	go a.doLoadVersionsAsync()
 }
 
 // Unmount implements the vecty.Unmounter interface.
@@ -544,6 +1010,18 @@ func (a *Application) onResize() {
 	a.editor.ResizeTextarea()
 }
 
+// toggleSidePane shows the pane in place of the help sidebar,
+// or hides it if it's already shown; it returns true if the pane
+// is now shown
+func (a *Application) toggleSidePane(name string) bool {
+	if a.sidePane == name {
+		a.sidePane = ""
+	} else {
+		a.sidePane = name
+	}
+	return a.sidePane == name
+}
+
 func (a *Application) settingsButtonClick(e *vecty.Event) {
 	a.showSettings = !a.showSettings
 	a.wantRerender("settingsButtonClick")
@@ -552,6 +1030,10 @@ func (a *Application) settingsButtonClick(e *vecty.Event) {
 func (a *Application) handleKeyDown(e *vecty.Event) {
 	switch e.Get("key").String() {
 	case "Escape":
+		if a.isCompiling {
+			a.doStop()
+			return
+		}
 		if a.isDrawingMode {
 			a.isDrawingMode = false
 			a.wantRerender("isDrawingMode switched off")
@@ -576,6 +1058,21 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 		a.undoStack = undo.NewStack(maxUndoStackSize)
 	}
 
+	if a.files == nil {
+		a.files = []*snippetFile{{
+			name:      txtar.MainFile,
+			undoStack: a.undoStack,
+		}}
+	}
+
+	if a.inputs == nil {
+		a.inputs = &programInputs{}
+	}
+
+	if a.profileKind == "" {
+		a.profileKind = profile.CPU
+	}
+
 	if a.modifierKey == "" {
 		a.modifierKey = "Ctrl"
 		if util.IsMacOS() {
@@ -601,14 +1098,39 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 	}
 	a.editor.WarningLines = a.warningLines
 	a.editor.ErrorLines = a.errorLines
+	a.editor.Annotations = a.files[a.activeFile].lineAnnotations()
+	a.editor.CoverageLines = a.files[a.activeFile].coverageLines
 	a.editor.Range = ranges.New(a.Hash.Ranges)
 	a.editor.HighlightingMode = a.HighlightingMode
 	a.editor.ReadonlyMode = a.isDrawingMode
 
 	a.log = &log.Log{
-		Error:  a.err,
-		Events: a.events,
-		HasRun: a.hasRun,
+		Error:     a.err,
+		Events:    a.events,
+		HasRun:    a.hasRun,
+		Cached:    a.isCached,
+		Running:   a.isCompiling,
+		Cancelled: a.isCancelled,
+
+		Status:      a.status,
+		IsTest:      a.isTest,
+		TestsFailed: a.testsFailed,
+		VetErrors:   a.vetErrors,
+		Coverage:    a.coverage,
+
+		Tests:        a.tests,
+		OnTestSelect: a.onTestSelect,
+
+		Fuzzing:        a.fuzzTarget != "",
+		FuzzProgress:   a.fuzzProgress,
+		Fuzz:           a.fuzz,
+		OnFuzz:         a.onFuzz,
+		OnAddFuzzInput: a.onAddFuzzInput,
+
+		Version:     a.runVersion,
+		BuildTarget: a.buildTarget,
+		Replay:      a.replay,
+		HTMLOutput:  a.HTMLOutput,
 	}
 
 	tabWidthClass := "tabwidth-" + strconv.Itoa(a.TabWidth)
@@ -622,7 +1144,7 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 			vecty.MarkupIf(util.IsSafari(), vecty.Class("safari")),
 			vecty.MarkupIf(util.IsIOS(), vecty.Class("ios")),
 			vecty.MarkupIf(a.isDrawingMode, vecty.Class("drawingmode")),
-			vecty.MarkupIf(a.ShowSidebar, vecty.Class("withsidebar")),
+			vecty.MarkupIf(a.ShowSidebar || a.sidePane != "", vecty.Class("withsidebar")),
 		),
 		elem.Div(
 			vecty.Markup(
@@ -650,6 +1172,30 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 						event.Click(a.runButtonClick),
 					),
 				),
+				elem.Button(
+					vecty.Markup(
+						vecty.Property("disabled", a.err != "" || a.isCompiling),
+						vecty.Attribute("title", "Run with CPU and heap profiling"),
+						vecty.UnsafeHTML("Profile"),
+						event.Click(a.profileButtonClick),
+					),
+				),
+				elem.Button(
+					vecty.Markup(
+						vecty.Property("disabled", a.err != "" || a.isCompiling),
+						vecty.Attribute("title", "Run with the execution tracer"),
+						vecty.UnsafeHTML("Trace"),
+						event.Click(a.traceButtonClick),
+					),
+				),
+				vecty.If(a.isCompiling, elem.Button(
+					vecty.Markup(
+						vecty.Class("stop"),
+						vecty.UnsafeHTML("Stop <cmd>Esc</cmd>"),
+						event.Click(a.stopButtonClick),
+					),
+				)),
+				a.getVersionSelector(),
 				elem.Button(
 					vecty.Markup(
 						vecty.Property("disabled", a.err != ""),
@@ -664,6 +1210,31 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 						event.Click(a.shareButtonClick),
 					),
 				),
+				elem.Button(
+					vecty.Markup(
+						vecty.MarkupIf(a.ShowInputs, vecty.Class("active")),
+						vecty.MarkupIf(!a.inputs.isEmpty(), vecty.Class("hasinputs")),
+						vecty.Attribute("title", "Standard input, arguments and environment variables"),
+						vecty.UnsafeHTML("Inputs"),
+						event.Click(a.inputsButtonClick),
+					),
+				),
+				elem.Button(
+					vecty.Markup(
+						vecty.MarkupIf(a.sidePane == asmPane, vecty.Class("active")),
+						vecty.Attribute("title", "Assembly generated by the compiler"),
+						vecty.UnsafeHTML("Assembly"),
+						event.Click(a.asmButtonClick),
+					),
+				),
+				elem.Button(
+					vecty.Markup(
+						vecty.MarkupIf(a.sidePane == ssaPane, vecty.Class("active")),
+						vecty.Attribute("title", "SSA form of the function under the caret"),
+						vecty.UnsafeHTML("SSA"),
+						event.Click(a.ssaButtonClick),
+					),
+				),
 			),
 			elem.Div(
 				vecty.Markup(
@@ -685,24 +1256,62 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 				vecty.Markup(
 					vecty.Class("content-wrapper"),
 				),
+				&tabs.Tabs{
+					Tabs:     a.getTabs(),
+					Active:   a.activeFile,
+					OnSelect: a.onTabSelect,
+					OnClose:  a.onTabClose,
+					OnAdd:    a.onTabAdd,
+				},
 				a.editor,
-				vecty.If(a.ShowSidebar, elem.Div(
+				vecty.If(a.ShowSidebar || a.sidePane != "", elem.Div(
 					vecty.Markup(
 						vecty.Class("help-wrapper"),
 					),
-					vecty.If(a.Topic == "" && !a.showDrawHelp, elem.Div(
+					vecty.If(a.sidePane == asmPane, &asm.Pane{
+						Functions:    a.asmFunctions,
+						Error:        a.asmError,
+						Loading:      a.isLoadingAsm,
+						Arch:         a.AsmArch,
+						File:         a.activeFileName(),
+						Range:        a.editor.Range,
+						OnArchChange: a.onAsmArchChange,
+						OnRefresh:    a.doLoadAsm,
+						OnSelect:     a.onSourceLineSelect,
+					}),
+					vecty.If(a.sidePane == ssaPane, &ssa.Pane{
+						Func:      a.ssaFunc,
+						HTML:      a.ssaHTML,
+						Error:     a.ssaError,
+						Loading:   a.isLoadingSSA,
+						OnRefresh: a.doLoadSSA,
+					}),
+					vecty.If(a.sidePane == profilePane, &profile.Pane{
+						Profile:      a.profile,
+						Kind:         a.profileKind,
+						Running:      a.isCompiling && a.profileRun,
+						OnKindChange: a.onProfileKindChange,
+						OnSelect:     a.onSourceLineSelect,
+					}),
+					vecty.If(a.sidePane == tracePane, &trace.Pane{
+						Trace:    a.trace,
+						Traced:   a.traced,
+						Running:  a.isCompiling && a.traceRun,
+						OnSelect: a.onSourceLineSelect,
+					}),
+					vecty.If(a.sidePane == "" && a.Topic == "" && !a.showDrawHelp, elem.Div(
 						vecty.Markup(
 							vecty.Class("help"),
 							vecty.UnsafeHTML(helpHTML),
 						),
 					)),
-					vecty.If(a.Topic == "" && a.showDrawHelp, elem.Div(
+					vecty.If(a.sidePane == "" && a.Topic == "" && a.showDrawHelp, elem.Div(
 						vecty.Markup(
 							vecty.Class("help"),
 							vecty.UnsafeHTML(drawHelpHTML),
 						),
 					)),
-					vecty.If(a.Topic != "", &help.Browser{
+					vecty.If(a.sidePane == "" && a.Topic != "", &help.Browser{
 						Imports: a.Imports,
 						Topic:   a.Topic,
 					}),
@@ -718,8 +1327,15 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 			elem.Div(
 				vecty.Markup(
 					vecty.Class("log-wrapper"),
+					vecty.MarkupIf(a.ShowInputs, vecty.Class("withinputs")),
 				),
 				a.log,
+				vecty.If(a.ShowInputs, &inputs.Panel{
+					Stdin:    a.inputs.stdin,
+					Args:     a.inputs.args,
+					Env:      a.inputs.env,
+					OnChange: a.onInputsChange,
+				}),
 				&splitter.Splitter{
 					Selector:         ".log-wrapper",
 					OppositeSelector: ".content-wrapper",
@@ -735,6 +1351,12 @@ func (a *Application) Render() vecty.ComponentOrHTML {
 			UseWebfont:       a.UseWebfont,
 			HighlightingMode: a.HighlightingMode,
 			ShowSidebar:      a.ShowSidebar,
+			RealTimeReplay:   a.RealTimeReplay,
+			HTMLOutput:       a.HTMLOutput,
+			Race:             a.Race,
+			BuildTags:        a.BuildTags,
+			BuildTarget:      a.BuildTarget,
+			OptDiagnostics:   a.OptDiagnostics,
 			OnChange:         a.onSettingsChange,
 		}),
 		vecty.If(a.isDrawingMode, &drawboard.DrawBoard{
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+
+	"honnef.co/go/js/xhr"
+
+	"github.com/gopherjs/vecty"
+	"github.com/iafan/goplayspace/client/api"
+	"github.com/iafan/goplayspace/client/js/localstorage"
+)
+
+func (a *Application) updateAsmArch(val string) {
+	a.AsmArch = val
+	localstorage.Set("asm-arch", val)
+	a.wantRerender("updateAsmArch")
+}
+
+func (a *Application) asmButtonClick(e *vecty.Event) {
+	if a.toggleSidePane(asmPane) {
+		a.doLoadAsm()
+	}
+	a.wantRerender("asmButtonClick")
+}
+
+func (a *Application) onAsmArchChange(arch string) {
+	a.updateAsmArch(arch)
+	a.doLoadAsm()
+}
+
+func (a *Application) doLoadAsm() {
+	a.isLoadingAsm = true
+	a.asmSeq++
+	go a.doLoadAsmAsync(a.asmSeq)
+}
+
+func (a *Application) doLoadAsmAsync(seq int) {
+	defer a.wantRerender("doLoadAsmAsync")
+
+	reqBytes, err := json.Marshal(&api.AsmRequest{
+		Body:    a.getSource(),
+		Version: a.Version,
+		GOARCH:  a.AsmArch,
+	})
+	if err != nil {
+		a.asmError = err.Error()
+		return
+	}
+
+	req := xhr.NewRequest("POST", "/api/v2/asm")
+	req.SetRequestHeader("Content-Type", "application/json")
+	err = req.Send(string(reqBytes))
+
+	// the snippet or the architecture has changed
+	// while the request was running
+	if seq != a.asmSeq {
+		return
+	}
+	a.isLoadingAsm = false
+	a.asmFunctions = nil
+	a.asmError = ""
+
+	if err != nil {
+		a.asmError = err.Error()
+		return
+	}
+	if req.Status != 200 {
+		a.asmError = strings.TrimSpace(req.ResponseText)
+		return
+	}
+
+	asmResponse := api.AsmResponse{}
+	if err := json.Unmarshal([]byte(req.ResponseText), &asmResponse); err != nil {
+		a.asmError = err.Error()
+		return
+	}
+	a.asmFunctions = asmResponse.Functions
+	a.asmError = asmResponse.Errors
+}
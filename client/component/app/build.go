@@ -0,0 +1,78 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/iafan/goplayspace/client/api"
+)
+
+const (
+	raceReportStart = "WARNING: DATA RACE"
+	raceReportEnd   = "=================="
+)
+
+// raceFrameR extracts file paths and line numbers from the stack frames
+// of data race reports, e.g. '      /tmp/x/main.go:12 +0x33'
+var raceFrameR = regexp.MustCompile(`(?m)^\s+(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+
+// splitBuildTarget splits 'GOOS/GOARCH' into GOOS and GOARCH
+func splitBuildTarget(target string) (string, string) {
+	tokens := strings.SplitN(target, "/", 2)
+	if len(tokens) != 2 {
+		return "", ""
+	}
+	return tokens[0], tokens[1]
+}
+
+// parseBuildTags splits the list of build tags
+// separated by commas or spaces
+func parseBuildTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// extractRaceLines maps the stack frames of data race reports
+// in the program output to the snippet files; the result
+// is keyed by file index
+func (a *Application) extractRaceLines(events []*api.CompileEvent) map[int]map[string]bool {
+	output := make([]string, len(events))
+	for i, evt := range events {
+		output[i] = evt.Message
+	}
+
+	out := make(map[int]map[string]bool)
+	reports := strings.Split(strings.Join(output, ""), raceReportStart)
+	for _, report := range reports[1:] {
+		if i := strings.Index(report, raceReportEnd); i >= 0 {
+			report = report[:i]
+		}
+		for _, m := range raceFrameR.FindAllStringSubmatch(report, -1) {
+			i := a.findFile(m[1])
+			if i == -1 {
+				continue
+			}
+			if out[i] == nil {
+				out[i] = make(map[string]bool)
+			}
+			out[i][m[2]] = true
+		}
+	}
+	return out
+}
+
+// addRaceErrors highlights lines from data race reports in all files
+func (a *Application) addRaceErrors(events []*api.CompileEvent) {
+	for i, lines := range a.extractRaceLines(events) {
+		f := a.files[i]
+		if f.errorLines == nil {
+			f.errorLines = make(map[string]bool)
+		}
+		for line := range lines {
+			f.errorLines[line] = true
+		}
+		f.hasErrors = true
+	}
+	a.errorLines = a.files[a.activeFile].errorLines
+}
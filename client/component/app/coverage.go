@@ -0,0 +1,34 @@
+package app
+
+import (
+	"strconv"
+
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// setCoverage saves the test coverage, and marks
+// the covered and uncovered lines of the files
+func (a *Application) setCoverage(coverage *api.Coverage) {
+	a.coverage = coverage
+	for _, f := range a.files {
+		f.coverageLines = nil
+	}
+	if coverage == nil {
+		return
+	}
+
+	for name, fc := range coverage.Files {
+		i := a.findFile(name)
+		if i == -1 {
+			continue
+		}
+		lines := make(map[string]bool)
+		for _, line := range fc.Covered {
+			lines[strconv.Itoa(line)] = true
+		}
+		for _, line := range fc.Uncovered {
+			lines[strconv.Itoa(line)] = false
+		}
+		a.files[i].coverageLines = lines
+	}
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"honnef.co/go/js/xhr"
+
+	"github.com/iafan/goplayspace/client/api"
+	"github.com/iafan/goplayspace/client/component/editor"
+	"github.com/iafan/goplayspace/client/js/console"
+	"github.com/iafan/goplayspace/client/js/localstorage"
+)
+
+// diagnosticPriority defines which diagnostic kind
+// is shown in the gutter if a line has several ones
+var diagnosticPriority = map[string]int{
+	api.MovedDiagnostic:  3,
+	api.EscapeDiagnostic: 2,
+	api.InlineDiagnostic: 1,
+}
+
+func (a *Application) updateOptDiagnostics(val bool) {
+	a.OptDiagnostics = val
+	localstorage.Set("opt-diagnostics", val)
+	if val {
+		go a.doLoadDiagnosticsAsync()
+	} else {
+		a.setDiagnostics(nil)
+	}
+	a.wantRerender("updateOptDiagnostics")
+}
+
+func (a *Application) doLoadDiagnosticsAsync() {
+	defer a.wantRerender("doLoadDiagnosticsAsync")
+
+	body := a.getSource()
+	reqBytes, err := json.Marshal(&api.DiagnosticsRequest{
+		Body:    body,
+		Version: a.Version,
+	})
+	if err != nil {
+		console.Log("Failed to encode the diagnostics request:", err.Error())
+		return
+	}
+
+	req := xhr.NewRequest("POST", "/api/v2/diagnostics")
+	req.SetRequestHeader("Content-Type", "application/json")
+	err = req.Send(string(reqBytes))
+	if err != nil || req.Status != 200 {
+		console.Log("Failed to load optimization diagnostics:", req.ResponseText)
+		return
+	}
+
+	diagnosticsResponse := api.DiagnosticsResponse{}
+	err = json.Unmarshal([]byte(req.ResponseText), &diagnosticsResponse)
+	if err != nil {
+		console.Log("Failed to decode optimization diagnostics:", err.Error())
+		return
+	}
+
+	// line numbers are no longer valid if the code
+	// has been edited while the request was running
+	if body != a.getSource() {
+		return
+	}
+	a.setDiagnostics(diagnosticsResponse.Diagnostics)
+}
+
+// setDiagnostics shows the optimization diagnostics
+// as line annotations in all files
+func (a *Application) setDiagnostics(diagnostics []*api.Diagnostic) {
+	for _, f := range a.files {
+		f.annotations = nil
+	}
+
+	for _, d := range diagnostics {
+		i := a.findFile(d.File)
+		if i == -1 {
+			continue
+		}
+		f := a.files[i]
+		if f.annotations == nil {
+			f.annotations = make(map[string]*editor.Annotation)
+		}
+
+		line := strconv.Itoa(d.Line)
+		an := f.annotations[line]
+		if an == nil {
+			f.annotations[line] = &editor.Annotation{Kind: d.Kind, Text: d.Message}
+			continue
+		}
+		an.Text += "\n" + d.Message
+		if diagnosticPriority[d.Kind] > diagnosticPriority[an.Kind] {
+			an.Kind = d.Kind
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package app
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/iafan/goplayspace/client/component/editor"
+	"github.com/iafan/goplayspace/client/component/editor/undo"
+	"github.com/iafan/goplayspace/client/component/tabs"
+	"github.com/iafan/goplayspace/client/js/window"
+	"github.com/iafan/goplayspace/txtar"
+)
+
+// upstreamMainFile is the name play.golang.org gives to the main file
+const upstreamMainFile = "prog.go"
+
+// fileLineExtractorR extracts file paths and line numbers
+// from compiler and vet messages, e.g. './util/util.go:12:3: ...'
+var fileLineExtractorR = regexp.MustCompile(`(?m)([\w\-./]+\.go):(\d+):`)
+
+// snippetFile is a single file of a (possibly multi-file) snippet
+type snippetFile struct {
+	name       string
+	text       string
+	undoStack  *undo.Stack
+	errorLines map[string]bool
+	hasErrors  bool
+
+	// annotations are the optimization diagnostics,
+	// and profileLines are the lines hot in the profile
+	annotations  map[string]*editor.Annotation
+	profileLines map[string]*editor.Annotation
+
+	// coverageLines tells which lines were run by the tests
+	coverageLines map[string]bool
+}
+
+func (a *Application) newSnippetFile(name, text string) *snippetFile {
+	return &snippetFile{
+		name:      name,
+		text:      text,
+		undoStack: undo.NewStack(maxUndoStackSize),
+	}
+}
+
+// lineAnnotations returns the annotations of the file lines;
+// the profile ones come first
+func (f *snippetFile) lineAnnotations() map[string]*editor.Annotation {
+	if f.profileLines == nil {
+		return f.annotations
+	}
+	if f.annotations == nil {
+		return f.profileLines
+	}
+
+	out := make(map[string]*editor.Annotation, len(f.annotations)+len(f.profileLines))
+	for line, an := range f.annotations {
+		out[line] = an
+	}
+	for line, an := range f.profileLines {
+		if other := out[line]; other != nil {
+			an = &editor.Annotation{Kind: an.Kind, Text: an.Text + "\n" + other.Text}
+		}
+		out[line] = an
+	}
+	return out
+}
+
+func (a *Application) activeFileName() string {
+	return a.files[a.activeFile].name
+}
+
+// getSource returns the source code of all files
+// in the play.golang.org txtar format
+func (a *Application) getSource() string {
+	a.files[a.activeFile].text = a.Input
+
+	files := make([]txtar.File, len(a.files))
+	for i, f := range a.files {
+		files[i] = txtar.File{Name: f.name, Data: []byte(f.text)}
+	}
+	return string(txtar.Join(files))
+}
+
+// setSource replaces all files with the ones from the source code
+// in the play.golang.org txtar format; if there's a file with the same
+// name as the currently active one, it stays active
+func (a *Application) setSource(src string) {
+	if src == a.getSource() {
+		return
+	}
+
+	activeName := a.activeFileName()
+	active := 0
+	oldFiles := a.files
+
+	a.files = nil
+	for i, f := range txtar.Split([]byte(src)) {
+		sf := a.newSnippetFile(f.Name, string(f.Data))
+		for _, old := range oldFiles {
+			if old.name == f.Name {
+				sf.undoStack = old.undoStack
+			}
+		}
+		if f.Name == activeName {
+			active = i
+		}
+		a.files = append(a.files, sf)
+	}
+
+	a.activeFile = active
+	a.loadActiveFile()
+}
+
+// loadActiveFile shows the active file in the editor
+func (a *Application) loadActiveFile() {
+	f := a.files[a.activeFile]
+	errorLines := f.errorLines
+	annotations := f.annotations
+	profileLines := f.profileLines
+	coverageLines := f.coverageLines
+	a.undoStack = f.undoStack
+	a.Input = f.text
+	a.parseAndReportErrors(f.text)
+	f.errorLines = errorLines
+	f.annotations = annotations
+	f.profileLines = profileLines
+	f.coverageLines = coverageLines
+	f.hasErrors = f.hasErrors || errorLines != nil
+	a.errorLines = errorLines
+	a.editor.Load(f.text, f.undoStack)
+	a.wantRerender("loadActiveFile")
+}
+
+func (a *Application) onTabSelect(i int) {
+	a.files[a.activeFile].text = a.Input
+	a.activeFile = i
+	a.loadActiveFile()
+}
+
+func (a *Application) onTabAdd() {
+	name, ok := window.Prompt("File name (e.g. go.mod, util/util.go):", "")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return
+	}
+
+	if strings.Contains(name, "..") || strings.HasPrefix(name, "/") {
+		window.Alert("Invalid file name: " + name)
+		return
+	}
+
+	for _, f := range a.files {
+		if f.name == name {
+			window.Alert("File already exists: " + name)
+			return
+		}
+	}
+
+	a.files[a.activeFile].text = a.Input
+	a.files = append(a.files, a.newSnippetFile(name, ""))
+	a.activeFile = len(a.files) - 1
+	a.loadActiveFile()
+	a.Hash.Reset()
+}
+
+func (a *Application) onTabClose(i int) {
+	if !window.Confirm("Remove " + a.files[i].name + "?") {
+		return
+	}
+
+	a.files[a.activeFile].text = a.Input
+	a.files = append(a.files[:i], a.files[i+1:]...)
+	if a.activeFile >= i && a.activeFile > 0 {
+		a.activeFile--
+	}
+	a.loadActiveFile()
+	a.Hash.Reset()
+}
+
+// findFile returns the index of the file that the path
+// from a compiler message refers to, or -1 if there's no such file
+func (a *Application) findFile(p string) int {
+	p = path.Clean(p)
+	if path.Base(p) == upstreamMainFile {
+		p = path.Join(path.Dir(p), txtar.MainFile)
+	}
+	for i, f := range a.files {
+		if p == f.name || strings.HasSuffix(p, "/"+f.name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// extractFileLines maps line numbers from compiler or vet messages
+// to the snippet files; the result is keyed by file index
+func (a *Application) extractFileLines(msg string) map[int]map[string]bool {
+	out := make(map[int]map[string]bool)
+	for _, m := range fileLineExtractorR.FindAllStringSubmatch(msg, -1) {
+		i := a.findFile(m[1])
+		if i == -1 {
+			continue
+		}
+		if out[i] == nil {
+			out[i] = make(map[string]bool)
+		}
+		out[i][m[2]] = true
+	}
+	return out
+}
+
+// setCompileErrors highlights lines with compilation errors in all files
+func (a *Application) setCompileErrors(msg string) {
+	lines := a.extractFileLines(msg)
+	for i, f := range a.files {
+		f.errorLines = lines[i]
+		f.hasErrors = lines[i] != nil
+	}
+	a.errorLines = a.files[a.activeFile].errorLines
+}
+
+// onSourceLineSelect shows and selects the line of the file
+// (e.g. the one an instruction was generated for)
+func (a *Application) onSourceLineSelect(file string, line int) {
+	i := a.findFile(file)
+	if i == -1 {
+		return
+	}
+	if i != a.activeFile {
+		a.onTabSelect(i)
+	}
+	a.Hash.SetRanges(strconv.Itoa(line))
+	a.editor.GoToLine(line)
+	a.wantRerender("onSourceLineSelect")
+}
+
+func (a *Application) getTabs() []*tabs.Tab {
+	out := make([]*tabs.Tab, len(a.files))
+	for i, f := range a.files {
+		out[i] = &tabs.Tab{
+			Title:     f.name,
+			Closable:  i > 0,
+			HasErrors: f.hasErrors,
+		}
+	}
+	return out
+}
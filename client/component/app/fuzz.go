@@ -0,0 +1,30 @@
+package app
+
+import (
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// onFuzz runs the snippet fuzzing the fuzz test
+// instead of running the tests
+func (a *Application) onFuzz(name string) {
+	if a.err != "" || a.isCompiling {
+		return
+	}
+	a.fuzzTarget = name
+	a.doRun()
+}
+
+// onAddFuzzInput adds the failing input found by the fuzzer
+// to the snippet as a seed corpus file, and shows the file
+func (a *Application) onAddFuzzInput(crash *api.FuzzCrash) {
+	if i := a.findFile(crash.File); i != -1 {
+		a.onTabSelect(i)
+		return
+	}
+
+	a.files[a.activeFile].text = a.Input
+	a.files = append(a.files, a.newSnippetFile(crash.File, crash.Input))
+	a.activeFile = len(a.files) - 1
+	a.loadActiveFile()
+	a.Hash.Reset()
+}
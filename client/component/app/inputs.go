@@ -0,0 +1,121 @@
+package app
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/iafan/goplayspace/txtar"
+)
+
+// Program inputs are saved with shared snippets as files
+// in the inputsDir directory of the archive; the go tool
+// ignores directories that start with an underscore
+const (
+	inputsDir = "_inputs/"
+	stdinFile = inputsDir + "stdin"
+	argsFile  = inputsDir + "args"
+	envFile   = inputsDir + "env"
+)
+
+// programInputs are the standard input, command-line arguments
+// and environment variables the program is run with
+type programInputs struct {
+	stdin string
+	args  string
+	env   string
+}
+
+func (in *programInputs) isEmpty() bool {
+	return in.stdin == "" && in.args == "" && in.env == ""
+}
+
+// splitInputs extracts the program inputs from the shared snippet,
+// and returns the rest of the snippet
+func splitInputs(src string) (string, *programInputs) {
+	in := &programInputs{}
+	if !txtar.IsArchive([]byte(src)) {
+		return src, in
+	}
+
+	var files []txtar.File
+	for _, f := range txtar.Split([]byte(src)) {
+		switch f.Name {
+		case stdinFile:
+			in.stdin = string(f.Data)
+		case argsFile:
+			in.args = strings.TrimSuffix(string(f.Data), "\n")
+		case envFile:
+			in.env = strings.TrimSuffix(string(f.Data), "\n")
+		default:
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		files = []txtar.File{{Name: txtar.MainFile}}
+	}
+	return string(txtar.Join(files)), in
+}
+
+// joinInputs adds the (non-empty) program inputs to the snippet
+func joinInputs(src string, in *programInputs) string {
+	if in.isEmpty() {
+		return src
+	}
+
+	files := txtar.Split([]byte(src))
+	add := func(name, data string) {
+		if data != "" {
+			files = append(files, txtar.File{Name: name, Data: []byte(data)})
+		}
+	}
+	add(stdinFile, in.stdin)
+	add(argsFile, in.args)
+	add(envFile, in.env)
+	return string(txtar.Join(files))
+}
+
+// parseArgs splits the command-line arguments by spaces;
+// single or double quotes can be used for arguments with spaces
+func parseArgs(s string) []string {
+	var out []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				out = append(out, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		out = append(out, arg.String())
+	}
+	return out
+}
+
+// parseEnv returns the KEY=VALUE pairs, one per line;
+// blank lines and lines without a key are skipped
+func parseEnv(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.IndexByte(line, '=') > 0 {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"strconv"
+
+	"github.com/gopherjs/vecty"
+	"github.com/iafan/goplayspace/client/component/editor"
+	"github.com/iafan/goplayspace/client/component/profile"
+)
+
+// hotLineFraction is the smallest share of the profile total
+// of the lines that are marked in the editor
+const hotLineFraction = 0.01
+
+func (a *Application) profileButtonClick(e *vecty.Event) {
+	a.profileRun = true
+	a.sidePane = profilePane
+	a.doRun()
+}
+
+func (a *Application) onProfileKindChange(kind string) {
+	a.profileKind = kind
+	a.setProfileLines()
+	a.wantRerender("onProfileKindChange")
+}
+
+// setProfileLines marks the lines of all files that are hot
+// in the profile of the selected kind
+func (a *Application) setProfileLines() {
+	for _, f := range a.files {
+		f.profileLines = nil
+	}
+
+	data := profile.Data(a.profile, a.profileKind)
+	if data == nil || data.Total == 0 {
+		return
+	}
+
+	type fileLine struct {
+		file int
+		line int
+	}
+	flat := make(map[fileLine]int64)
+	cum := make(map[fileLine]int64)
+	for _, s := range data.Samples {
+		seen := make(map[fileLine]bool)
+		for i, frameIndex := range s.Stack {
+			frame := data.Frames[frameIndex]
+			file := a.findFile(frame.File)
+			if file == -1 {
+				continue
+			}
+			fl := fileLine{file, frame.Line}
+			if !seen[fl] {
+				cum[fl] += s.Value
+				seen[fl] = true
+			}
+			if i == len(s.Stack)-1 {
+				flat[fl] += s.Value
+			}
+		}
+	}
+
+	title := "CPU"
+	if a.profileKind == profile.Heap {
+		title = "Allocations"
+	}
+	for fl, v := range cum {
+		if float64(v) < float64(data.Total)*hotLineFraction {
+			continue
+		}
+		f := a.files[fl.file]
+		if f.profileLines == nil {
+			f.profileLines = make(map[string]*editor.Annotation)
+		}
+		f.profileLines[strconv.Itoa(fl.line)] = &editor.Annotation{
+			Kind: "hot",
+			Text: title + ": " + profile.FormatValue(data.Unit, flat[fl]) + " flat, " +
+				profile.FormatValue(data.Unit, v) + " cum (" +
+				strconv.FormatFloat(float64(v)*100/float64(data.Total), 'f', 1, 64) + "%)",
+		}
+	}
+}
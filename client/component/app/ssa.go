@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"honnef.co/go/js/xhr"
+
+	"github.com/gopherjs/vecty"
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// funcDecl is the location of a function declaration
+// of the active file
+type funcDecl struct {
+	// name is the GOSSAFUNC name, e.g. 'main.(*T).String'
+	name       string
+	begin, end int
+}
+
+// collectFuncDecls remembers the locations of the functions
+// of the parsed file
+func (a *Application) collectFuncDecls(fset *token.FileSet, f *ast.File) {
+	if f == nil {
+		return
+	}
+
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		a.funcDecls = append(a.funcDecls, &funcDecl{
+			name:  f.Name.Name + "." + funcName(fn),
+			begin: fset.Position(fn.Pos()).Offset,
+			end:   fset.Position(fn.End()).Offset,
+		})
+	}
+}
+
+// funcName returns the name of the function
+// as the compiler reports it, e.g. '(*T).String'
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+
+	typ := fn.Recv.List[0].Type
+	star := false
+	if s, ok := typ.(*ast.StarExpr); ok {
+		typ = s.X
+		star = true
+	}
+	// type parameters of generic receivers are omitted
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	recv, ok := typ.(*ast.Ident)
+	if !ok {
+		return fn.Name.Name
+	}
+	if star {
+		return "(*" + recv.Name + ")." + fn.Name.Name
+	}
+	return recv.Name + "." + fn.Name.Name
+}
+
+// funcAtCaret returns the name of the function under the caret,
+// or an empty string if the caret is outside of functions
+func (a *Application) funcAtCaret() string {
+	pos, _ := a.editor.GetSelection()
+	for _, fn := range a.funcDecls {
+		if pos >= fn.begin && pos <= fn.end {
+			return fn.name
+		}
+	}
+	return ""
+}
+
+func (a *Application) ssaButtonClick(e *vecty.Event) {
+	if a.toggleSidePane(ssaPane) {
+		a.doLoadSSA()
+	}
+	a.wantRerender("ssaButtonClick")
+}
+
+// doLoadSSA loads the SSA form of the function under the caret
+func (a *Application) doLoadSSA() {
+	a.ssaSeq++
+	a.ssaFunc = a.funcAtCaret()
+	a.ssaHTML = ""
+	if a.ssaFunc == "" {
+		a.isLoadingSSA = false
+		a.ssaError = "Place the caret inside a function to see its SSA form."
+		a.wantRerender("doLoadSSA")
+		return
+	}
+	a.ssaError = ""
+	a.isLoadingSSA = true
+	go a.doLoadSSAAsync(a.ssaSeq)
+}
+
+func (a *Application) doLoadSSAAsync(seq int) {
+	defer a.wantRerender("doLoadSSAAsync")
+
+	reqBytes, err := json.Marshal(&api.SSARequest{
+		Body:    a.getSource(),
+		Version: a.Version,
+		Func:    a.ssaFunc,
+	})
+	if err != nil {
+		a.ssaError = err.Error()
+		return
+	}
+
+	req := xhr.NewRequest("POST", "/api/v2/ssa")
+	req.SetRequestHeader("Content-Type", "application/json")
+	err = req.Send(string(reqBytes))
+
+	// another function has been requested
+	// while the request was running
+	if seq != a.ssaSeq {
+		return
+	}
+	a.isLoadingSSA = false
+
+	if err != nil {
+		a.ssaError = err.Error()
+		return
+	}
+	if req.Status != 200 {
+		a.ssaError = strings.TrimSpace(req.ResponseText)
+		return
+	}
+
+	ssaResponse := api.SSAResponse{}
+	if err := json.Unmarshal([]byte(req.ResponseText), &ssaResponse); err != nil {
+		a.ssaError = err.Error()
+		return
+	}
+	a.ssaHTML = ssaResponse.HTML
+	a.ssaError = ssaResponse.Errors
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testFuncPrefixes are the name prefixes of functions run by 'go test'
+var testFuncPrefixes = []string{"Test", "Benchmark", "Example", "Fuzz"}
+
+// testFunc is the location of a test, benchmark or example function
+type testFunc struct {
+	file int
+	line int
+}
+
+// isTestFuncName tells if the function with this name is run
+// by 'go test' (the rules are the same as in 'go test')
+func isTestFuncName(name string) bool {
+	for _, prefix := range testFuncPrefixes {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if len(name) == len(prefix) {
+			return true
+		}
+		r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+		if !unicode.IsLower(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectTestFuncs remembers the locations of the test functions
+// of the parsed file, and whether the file has the main function
+func (a *Application) collectTestFuncs(file int, fset *token.FileSet, f *ast.File) {
+	if f == nil {
+		return
+	}
+
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+
+		name := fn.Name.Name
+		if name == "main" {
+			a.hasMainFunc = true
+			continue
+		}
+
+		if isTestFuncName(name) {
+			a.testFuncs[name] = &testFunc{
+				file: file,
+				line: fset.Position(fn.Pos()).Line,
+			}
+		}
+	}
+}
+
+// isTestMode tells if the snippet should be run with 'go test':
+// like play.golang.org, this is the case for snippets
+// that have test functions and no main function
+func (a *Application) isTestMode() bool {
+	return len(a.testFuncs) > 0 && !a.hasMainFunc
+}
+
+// onTestSelect shows the function of the test in the editor
+func (a *Application) onTestSelect(name string) {
+	// subtests are reported as 'TestFoo/bar'
+	name = strings.SplitN(name, "/", 2)[0]
+
+	fn, ok := a.testFuncs[name]
+	if !ok {
+		return
+	}
+
+	if fn.file != a.activeFile {
+		a.onTabSelect(fn.file)
+	}
+	a.editor.GoToLine(fn.line)
+}
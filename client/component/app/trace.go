@@ -0,0 +1,11 @@
+package app
+
+import (
+	"github.com/gopherjs/vecty"
+)
+
+func (a *Application) traceButtonClick(e *vecty.Event) {
+	a.traceRun = true
+	a.sidePane = tracePane
+	a.doRun()
+}
@@ -0,0 +1,185 @@
+package asm
+
+import (
+	"strconv"
+
+	"github.com/gopherjs/gopherjs/js"
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+	"github.com/iafan/goplayspace/client/js/document"
+	"github.com/iafan/goplayspace/client/ranges"
+	"github.com/iafan/goplayspace/client/util"
+)
+
+// Archs are the GOARCH values offered in the pane;
+// the empty value is the server architecture
+var Archs = []string{"", "amd64", "arm64", "386", "arm", "riscv64", "ppc64le", "s390x", "mips64", "loong64"}
+
+// Pane contains the logic behind the assembly pane
+// exposed on the application page under '.asm' class
+type Pane struct {
+	vecty.Core
+
+	Functions []*api.AsmFunction `vecty:"prop"`
+	Error     string             `vecty:"prop"`
+	Loading   bool               `vecty:"prop"`
+	Arch      string             `vecty:"prop"`
+
+	// File is the name of the file shown in the editor,
+	// and Range is its line selection; instructions
+	// generated for the selected lines are highlighted
+	File  string        `vecty:"prop"`
+	Range *ranges.Range `vecty:"prop"`
+
+	OnArchChange func(arch string)
+	OnRefresh    func()
+	OnSelect     func(file string, line int)
+}
+
+func (p *Pane) archChange(e *vecty.Event) {
+	if p.OnArchChange != nil {
+		p.OnArchChange(e.Target.Get("value").String())
+	}
+}
+
+func (p *Pane) refreshClick(e *vecty.Event) {
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+func (p *Pane) getArchSelector() *vecty.HTML {
+	options := []vecty.MarkupOrChild{
+		vecty.Markup(
+			event.Change(p.archChange),
+		),
+	}
+	for _, arch := range Archs {
+		title := arch
+		if arch == "" {
+			title = "Server GOARCH"
+		}
+		options = append(options, elem.Option(
+			vecty.Markup(
+				vecty.Property("value", arch),
+				vecty.Property("selected", p.Arch == arch),
+			),
+			vecty.Text(title),
+		))
+	}
+	return elem.Select(options...)
+}
+
+func (p *Pane) getInstruction(instr *api.AsmInstruction) *vecty.HTML {
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("instr"),
+			vecty.MarkupIf(instr.File == p.File && p.Range.IsLineSelected(instr.Line), vecty.Class("selected")),
+			event.Click(func(e *vecty.Event) {
+				if p.OnSelect != nil {
+					p.OnSelect(instr.File, instr.Line)
+				}
+			}),
+		),
+		elem.Span(
+			vecty.Markup(
+				vecty.Class("line"),
+			),
+			vecty.Text(instr.File+":"+strconv.Itoa(instr.Line)),
+		),
+		elem.Span(
+			vecty.Markup(
+				vecty.Class("text"),
+			),
+			vecty.Text(instr.Text),
+		),
+	)
+}
+
+func (p *Pane) getFunctions() []vecty.MarkupOrChild {
+	out := make([]vecty.MarkupOrChild, 0, len(p.Functions))
+	for _, fn := range p.Functions {
+		instructions := make([]vecty.MarkupOrChild, 0, len(fn.Instructions)+2)
+		instructions = append(instructions,
+			vecty.Markup(
+				vecty.Class("function"),
+			),
+			elem.Div(
+				vecty.Markup(
+					vecty.Class("name"),
+				),
+				vecty.Text(fn.Name),
+			),
+		)
+		for _, instr := range fn.Instructions {
+			instructions = append(instructions, p.getInstruction(instr))
+		}
+		out = append(out, elem.Div(instructions...))
+	}
+	return out
+}
+
+// scrollToSelection scrolls the first highlighted instruction into view
+func (p *Pane) scrollToSelection() {
+	if !p.Range.HasSelection() {
+		return
+	}
+	util.Schedule(func() {
+		instr := document.QuerySelector(".asm .instr.selected")
+		if instr != nil {
+			instr.Call("scrollIntoView", js.M{"block": "nearest"})
+		}
+	})
+}
+
+// Render implements the vecty.Component interface.
+func (p *Pane) Render() vecty.ComponentOrHTML {
+	p.scrollToSelection()
+
+	status := ""
+	if p.Loading {
+		status = "Compiling…"
+	} else if p.Error == "" && len(p.Functions) == 0 {
+		status = "No functions."
+	}
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("asm"),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("toolbar"),
+			),
+			p.getArchSelector(),
+			elem.Button(
+				vecty.Markup(
+					vecty.Property("disabled", p.Loading),
+					event.Click(p.refreshClick),
+				),
+				vecty.Text("Refresh"),
+			),
+			elem.Span(
+				vecty.Markup(
+					vecty.Class("status"),
+				),
+				vecty.Text(status),
+			),
+		),
+		elem.Div(
+			append([]vecty.MarkupOrChild{
+				vecty.Markup(
+					vecty.Class("listing"),
+				),
+				vecty.If(p.Error != "", elem.Div(
+					vecty.Markup(
+						vecty.Class("error"),
+					),
+					vecty.Text(p.Error),
+				)),
+			}, p.getFunctions()...)...,
+		),
+	)
+}
@@ -23,27 +23,40 @@ import (
 // onChange event for state to be saved to undo stack
 const saveStateTimeout = 500 * time.Millisecond
 
+// Annotation is a note on a line, which is shown
+// as a marker in the gutter and as its tooltip
+type Annotation struct {
+	// Kind defines the marker color
+	// (see --annotation-<kind>-color in style.css)
+	Kind string
+	Text string
+}
+
 // Editor implements editor logic
 type Editor struct {
 	vecty.Core
 
-	ta          *textarea.Textarea
-	sh          *Shadow
-	shiftDown   bool
-	ctrlDown    bool
-	metaDown    bool
-	highlighted string
-	selLinesCSS string
-	errorsCSS   string
-	warningsCSS string
-
-	Range            *ranges.Range   `vecty:"prop"`
-	HighlightingMode bool            `vecty:"prop"`
-	ReadonlyMode     bool            `vecty:"prop"`
-	ErrorLines       map[string]bool `vecty:"prop"`
-	WarningLines     map[string]bool `vecty:"prop"`
-	UndoStack        *undo.Stack     `vecty:"prop"`
-	ChangeTimer      **time.Timer    // note this is a pointer to a pointer
+	ta             *textarea.Textarea
+	sh             *Shadow
+	shiftDown      bool
+	ctrlDown       bool
+	metaDown       bool
+	highlighted    string
+	selLinesCSS    string
+	errorsCSS      string
+	warningsCSS    string
+	annotationsCSS string
+	coverageCSS    string
+
+	Range            *ranges.Range          `vecty:"prop"`
+	HighlightingMode bool                   `vecty:"prop"`
+	ReadonlyMode     bool                   `vecty:"prop"`
+	ErrorLines       map[string]bool        `vecty:"prop"`
+	WarningLines     map[string]bool        `vecty:"prop"`
+	Annotations      map[string]*Annotation `vecty:"prop"`
+	CoverageLines    map[string]bool        `vecty:"prop"` // line number -> covered by tests
+	UndoStack        *undo.Stack            `vecty:"prop"`
+	ChangeTimer      **time.Timer           // note this is a pointer to a pointer
 
 	Highlighter     func(s string) string `vecty:"prop"`
 	OnTopicChange   func(topic string)
@@ -78,6 +91,35 @@ func (ed *Editor) SetSelection(start, end int) {
 	ed.ta.SetSelectionEnd(end)
 }
 
+// GoToLine moves the caret to the beginning of the line
+// and scrolls the line into view
+func (ed *Editor) GoToLine(n int) {
+	if ed.ta == nil {
+		return
+	}
+
+	text := ed.ta.GetValue()
+	pos := 0
+	for i := 1; i < n; i++ {
+		j := strings.IndexByte(text[pos:], '\n')
+		if j == -1 {
+			break
+		}
+		pos += j + 1
+	}
+
+	ed.SetSelection(pos, pos)
+	ed.Focus()
+
+	// the shadow can be rendered later if the text has just been replaced
+	util.Schedule(func() {
+		li := document.QuerySelector(".shadow ol li:nth-child(" + strconv.Itoa(n) + ")")
+		if li != nil {
+			li.Call("scrollIntoView", js.M{"block": "center"})
+		}
+	})
+}
+
 func (ed *Editor) updateSelectionInfo(e *vecty.Event) {
 	if ed.ta == nil || ed.OnTopicChange == nil {
 		return
@@ -164,6 +206,8 @@ func (ed *Editor) onChange(e *vecty.Event) {
 	ed.Range = nil
 	ed.WarningLines = nil
 	ed.ErrorLines = nil
+	ed.Annotations = nil
+	ed.CoverageLines = nil
 	ed.Highlight(ed.HighlightingMode)
 
 	t := *ed.ChangeTimer
@@ -493,6 +537,12 @@ func (ed *Editor) afterRender() {
 	for i := 0; i < n; i++ {
 		list.Index(i).Set("onmousedown", ed.handleShadowMouseDown)
 		list.Index(i).Set("data-index", i+1)
+
+		title := ""
+		if a := ed.Annotations[strconv.Itoa(i+1)]; a != nil {
+			title = a.Text
+		}
+		list.Index(i).Set("title", title)
 	}
 }
 
@@ -530,6 +580,30 @@ func (ed *Editor) updateStateFromWarnings() {
 	}
 }
 
+func (ed *Editor) updateStateFromCoverage() {
+	ed.coverageCSS = ""
+	if ed.CoverageLines == nil {
+		return
+	}
+	for key, covered := range ed.CoverageLines {
+		color := "var(--uncovered-bgcolor)"
+		if covered {
+			color = "var(--covered-bgcolor)"
+		}
+		ed.coverageCSS = ed.coverageCSS + ".shadow ol li:nth-child(" + key + ") {background: " + color + "}\n"
+	}
+}
+
+func (ed *Editor) updateStateFromAnnotations() {
+	ed.annotationsCSS = ""
+	if ed.Annotations == nil {
+		return
+	}
+	for key, a := range ed.Annotations {
+		ed.annotationsCSS = ed.annotationsCSS + ".shadow ol li:nth-child(" + key + ")::before {box-shadow: inset -3px 0 var(--annotation-" + a.Kind + "-color)}\n"
+	}
+}
+
 // Mount implements the vecty.Mounter interface.
 func (ed *Editor) Mount() {
 	obj := document.QuerySelector(".editor")
@@ -547,9 +621,11 @@ func (ed *Editor) Mount() {
 
 // Render implements the vecty.Component interface.
 func (ed *Editor) Render() vecty.ComponentOrHTML {
+	ed.updateStateFromCoverage()
 	ed.updateStateFromRanges()
 	ed.updateStateFromWarnings()
 	ed.updateStateFromErrors()
+	ed.updateStateFromAnnotations()
 	util.Schedule(ed.afterRender)
 
 	return elem.Div(
@@ -580,6 +656,11 @@ func (ed *Editor) Render() vecty.ComponentOrHTML {
 				event.ContextMenu(ed.cancelEvent),
 			),
 		),
+		elem.Style(
+			vecty.Markup(
+				vecty.UnsafeHTML(ed.coverageCSS),
+			),
+		),
 		elem.Style(
 			vecty.Markup(
 				vecty.UnsafeHTML(ed.selLinesCSS),
@@ -595,5 +676,10 @@ func (ed *Editor) Render() vecty.ComponentOrHTML {
 				vecty.UnsafeHTML(ed.errorsCSS),
 			),
 		),
+		elem.Style(
+			vecty.Markup(
+				vecty.UnsafeHTML(ed.annotationsCSS),
+			),
+		),
 	)
 }
@@ -1,6 +1,9 @@
 package editor
 
-import "github.com/iafan/goplayspace/client/component/editor/undo"
+import (
+	"github.com/iafan/goplayspace/client/component/editor/undo"
+	"github.com/iafan/goplayspace/client/js/console"
+)
 
 func (ed *Editor) getStateAsUndoEntry() *undo.Entry {
 	return &undo.Entry{
@@ -70,3 +73,17 @@ func (ed *Editor) Redo() {
 	ed.ta.SetSelectionEnd(entry.SelEnd)
 	ed.onChange(nil)
 }
+
+// Load replaces the editor text and the undo stack
+// (e.g. when switching between files)
+func (ed *Editor) Load(text string, stack *undo.Stack) {
+	if ed.ta == nil {
+		console.Log("editor.Load() getTextarea() is nil")
+		return
+	}
+	ed.saveState()
+	ed.UndoStack = stack
+	ed.ta.SetValue(text)
+	ed.saveState()
+	ed.onChange(nil)
+}
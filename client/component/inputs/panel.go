@@ -0,0 +1,102 @@
+package inputs
+
+import (
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+)
+
+// Panel contains the logic behind the program inputs panel
+// exposed on the application page under '.inputs' class
+type Panel struct {
+	vecty.Core
+
+	// Stdin is the program standard input
+	Stdin string `vecty:"prop"`
+
+	// Args are the command-line arguments, separated by spaces
+	// (quotes can be used for arguments with spaces)
+	Args string `vecty:"prop"`
+
+	// Env are the environment variables, one KEY=VALUE pair per line
+	Env string `vecty:"prop"`
+
+	OnChange func(p *Panel)
+}
+
+func (p *Panel) updateStdin(e *vecty.Event) {
+	p.Stdin = e.Target.Get("value").String()
+	p.fireOnChangeEvent()
+}
+
+func (p *Panel) updateArgs(e *vecty.Event) {
+	p.Args = e.Target.Get("value").String()
+	p.fireOnChangeEvent()
+}
+
+func (p *Panel) updateEnv(e *vecty.Event) {
+	p.Env = e.Target.Get("value").String()
+	p.fireOnChangeEvent()
+}
+
+func (p *Panel) fireOnChangeEvent() {
+	if p.OnChange != nil {
+		p.OnChange(p)
+	}
+}
+
+// Render implements the vecty.Component interface.
+func (p *Panel) Render() vecty.ComponentOrHTML {
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("inputs"),
+		),
+		elem.Label(
+			vecty.Markup(
+				vecty.Attribute("for", "inputs-args"),
+			),
+			vecty.Text("Arguments:"),
+		),
+		elem.Input(
+			vecty.Markup(
+				vecty.Property("id", "inputs-args"),
+				vecty.Property("type", "text"),
+				vecty.Property("value", p.Args),
+				vecty.Attribute("placeholder", `-n 5 "two words"`),
+				vecty.Attribute("spellcheck", "false"),
+				event.Input(p.updateArgs),
+			),
+		),
+		elem.Label(
+			vecty.Markup(
+				vecty.Attribute("for", "inputs-env"),
+			),
+			vecty.Text("Environment:"),
+		),
+		elem.TextArea(
+			vecty.Markup(
+				vecty.Class("env"),
+				vecty.Property("id", "inputs-env"),
+				vecty.Property("value", p.Env),
+				vecty.Attribute("placeholder", "KEY=VALUE"),
+				vecty.Attribute("spellcheck", "false"),
+				event.Input(p.updateEnv),
+			),
+		),
+		elem.Label(
+			vecty.Markup(
+				vecty.Attribute("for", "inputs-stdin"),
+			),
+			vecty.Text("Standard input:"),
+		),
+		elem.TextArea(
+			vecty.Markup(
+				vecty.Class("stdin"),
+				vecty.Property("id", "inputs-stdin"),
+				vecty.Property("value", p.Stdin),
+				vecty.Attribute("spellcheck", "false"),
+				event.Input(p.updateStdin),
+			),
+		),
+	)
+}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"strconv"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// describeFuzzProgress returns the progress of the fuzzing session,
+// e.g. '3s, 313439 execs (104460/sec), 2 new interesting inputs'
+func describeFuzzProgress(p *api.FuzzProgress) string {
+	inputs := " new interesting inputs"
+	if p.NewInteresting == 1 {
+		inputs = " new interesting input"
+	}
+	return strconv.Itoa(p.Elapsed) + "s, " +
+		strconv.FormatInt(p.Execs, 10) + " execs (" +
+		strconv.FormatInt(p.ExecsPerSec, 10) + "/sec), " +
+		strconv.Itoa(p.NewInteresting) + inputs
+}
+
+// getFuzzProgressText returns the final line text
+// while the fuzzing session is running
+func (l *Log) getFuzzProgressText() string {
+	if l.FuzzProgress == nil {
+		return "Fuzzing…"
+	}
+	return "Fuzzing… " + describeFuzzProgress(l.FuzzProgress)
+}
+
+// getFuzzFinal returns the final line text for the finished
+// fuzzing session, and whether it failed
+func (l *Log) getFuzzFinal() (string, bool) {
+	switch {
+	case l.Fuzz.Crash != nil:
+		return "Fuzzing " + l.Fuzz.Name + " found a failing input.", true
+	case l.Status != 0:
+		return "Fuzzing " + l.Fuzz.Name + " failed.", true
+	case l.Fuzz.Progress != nil:
+		return "Fuzzing " + l.Fuzz.Name + " found no failures: " + describeFuzzProgress(l.Fuzz.Progress) + ".", false
+	}
+	return "Fuzzing " + l.Fuzz.Name + " found no failures.", false
+}
+
+// getFuzzButton renders the button that starts fuzzing the fuzz test
+// (the seed corpus entries are reported as its subtests)
+func (l *Log) getFuzzButton(t *api.TestResult, isSubtest bool) vecty.MarkupOrChild {
+	if t.Kind != "fuzz" || isSubtest || l.OnFuzz == nil || l.Running {
+		return nil
+	}
+	return elem.Button(
+		vecty.Markup(
+			vecty.Class("fuzz"),
+			vecty.Attribute("title", "Fuzz "+t.Name+" for a limited time"),
+			event.Click(func(e *vecty.Event) {
+				// don't go to the test function
+				e.Call("stopPropagation")
+				l.OnFuzz(t.Name)
+			}),
+		),
+		vecty.Text("Fuzz"),
+	)
+}
+
+// getFuzzCrash renders the failing input found by the fuzzer
+func (l *Log) getFuzzCrash() vecty.MarkupOrChild {
+	if l.Fuzz == nil || l.Fuzz.Crash == nil || l.Running {
+		return nil
+	}
+	crash := l.Fuzz.Crash
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("fuzz-crash"),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("title"),
+			),
+			vecty.Text("Failing input ("+crash.File+"):"),
+			vecty.If(l.OnAddFuzzInput != nil, elem.Button(
+				vecty.Markup(
+					vecty.Attribute("title", "Add the input to the snippet, so that the tests run it"),
+					event.Click(func(e *vecty.Event) {
+						l.OnAddFuzzInput(crash)
+					}),
+				),
+				vecty.Text("Add to seed corpus"),
+			)),
+		),
+		vecty.Text(crash.Input),
+	)
+}
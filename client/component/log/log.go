@@ -2,6 +2,7 @@ package log
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gopherjs/gopherjs/js"
@@ -11,6 +12,9 @@ import (
 	"github.com/iafan/goplayspace/client/js/document"
 )
 
+// formFeed clears the log, see getEvents
+const formFeed = "\f"
+
 var hour = 60 * time.Minute
 var day = 24 * hour
 
@@ -23,13 +27,71 @@ type Log struct {
 	Error  string              `vecty:"prop"`
 	Events []*api.CompileEvent `vecty:"prop"`
 	HasRun bool                `vecty:"prop"`
+	Cached bool                `vecty:"prop"`
+
+	// Running is true while the program output is being streamed,
+	// and Cancelled is true if the run was cancelled by the user
+	Running   bool `vecty:"prop"`
+	Cancelled bool `vecty:"prop"`
+
+	Status      int    `vecty:"prop"`
+	IsTest      bool   `vecty:"prop"`
+	TestsFailed int    `vecty:"prop"`
+	VetErrors   string `vecty:"prop"`
+
+	// Coverage is the test coverage, if reported
+	Coverage *api.Coverage `vecty:"prop"`
+
+	Tests        []*api.TestResult `vecty:"prop"`
+	OnTestSelect func(name string)
+
+	// Fuzzing is true if the current run is a fuzzing session,
+	// FuzzProgress is its latest progress, and Fuzz is the result
+	// of the last session
+	Fuzzing      bool              `vecty:"prop"`
+	FuzzProgress *api.FuzzProgress `vecty:"prop"`
+	Fuzz         *api.FuzzResult   `vecty:"prop"`
+
+	// OnFuzz starts fuzzing the fuzz test, and OnAddFuzzInput
+	// adds the failing input to the seed corpus
+	OnFuzz         func(name string)
+	OnAddFuzzInput func(crash *api.FuzzCrash)
+
+	// Version is the Go version that produced the output
+	Version string `vecty:"prop"`
+
+	// BuildTarget is the GOOS/GOARCH the program was only built for
+	BuildTarget string `vecty:"prop"`
+
+	// Replay, if not nil, reveals the events in real time
+	Replay *Replay `vecty:"prop"`
+
+	// HTMLOutput tells to render 'HTML:' lines as sanitized HTML;
+	// otherwise, they are shown as plain text
+	HTMLOutput bool `vecty:"prop"`
 }
 
 func (l *Log) getEvents() []vecty.MarkupOrChild {
-	if len(l.Events) == 0 {
+	if len(l.Tests) > 0 {
+		return l.getTestResults()
+	}
+
+	events := l.Events
+	replaying := l.Replay != nil && !l.Replay.Done()
+	if replaying {
+		events = events[:l.Replay.Shown]
+	}
+
+	if len(events) == 0 {
+		if replaying {
+			return []vecty.MarkupOrChild{l.getReplayControls()}
+		}
+		if l.HasRun && (l.Status != 0 || l.Running || l.Cancelled) {
+			return []vecty.MarkupOrChild{l.getFinal()}
+		}
 		return nil
 	}
-	out := make([]vecty.MarkupOrChild, len(l.Events)+1)
+	out := make([]vecty.MarkupOrChild, 0, len(events)+1)
 
 	var totalDelay time.Duration
 	for _, evt := range l.Events {
@@ -49,16 +111,35 @@ func (l *Log) getEvents() []vecty.MarkupOrChild {
 		format = "T+05.000000000"
 	}
 
+	// a form feed character clears the screen (this is used
+	// by terminal animations), so only the output after the last
+	// form feed is shown
+	clearIndex := -1
+	for i, evt := range events {
+		if strings.Contains(evt.Message, formFeed) {
+			clearIndex = i
+		}
+	}
+
 	deltaTime := time.Time{}
 	var deltaDuration time.Duration
-	for i, evt := range l.Events {
+	for i, evt := range events {
 		deltaTime = deltaTime.Add(evt.Delay)
 		deltaDuration += evt.Delay
+		if i < clearIndex {
+			continue
+		}
+
+		message := evt.Message
+		if i == clearIndex {
+			message = message[strings.LastIndex(message, formFeed)+len(formFeed):]
+		}
+
 		text := deltaTime.Format(format)
 		if totalDays > 0 {
 			text = "D+" + strconv.Itoa(int(deltaDuration/day)) + " " + text
 		}
-		out[i] = elem.Div(
+		line := []vecty.MarkupOrChild{
 			vecty.Markup(
 				vecty.Class(evt.Kind),
 			),
@@ -68,25 +149,91 @@ func (l *Log) getEvents() []vecty.MarkupOrChild {
 				),
 				vecty.Text(text),
 			)),
-			vecty.Text(evt.Message),
-		)
+		}
+		out = append(out, elem.Div(append(line, l.renderOutput(message)...)...))
 	}
 
+	if replaying {
+		return append(out, l.getReplayControls())
+	}
+	return append(out, l.getFinal())
+}
+
+func (l *Log) getFinal() *vecty.HTML {
 	final := ""
+	failed := false
 	if l.HasRun {
 		final = "Program exited."
 		if len(l.Events) == 0 {
 			final = "Program exited producing no output."
 		}
+		if l.Status != 0 {
+			final = "Program exited: status " + strconv.Itoa(l.Status) + "."
+			failed = true
+		}
+		if l.IsTest {
+			final = "All tests passed."
+			if l.TestsFailed == 1 {
+				final = "1 test failed."
+			}
+			if l.TestsFailed > 1 {
+				final = strconv.Itoa(l.TestsFailed) + " tests failed."
+			}
+			failed = l.TestsFailed > 0
+		}
+		if l.Fuzz != nil {
+			final, failed = l.getFuzzFinal()
+		}
+		if l.BuildTarget != "" {
+			final = "Built for " + l.BuildTarget + " (programs for other platforms are not run)."
+		}
+		if l.Cached {
+			final += " (cached result)"
+		}
+		if l.Running {
+			final = "Running…"
+			if l.Fuzzing {
+				final = l.getFuzzProgressText()
+			}
+			failed = false
+		}
+		if l.Cancelled {
+			final = "Run cancelled."
+			failed = true
+		}
 	}
 
-	out[len(out)-1] = elem.Div(
+	return elem.Div(
 		vecty.Markup(
 			vecty.Class("final"),
+			vecty.MarkupIf(failed, vecty.Class("failed")),
 		),
 		vecty.Text(final),
+		vecty.If(l.HasRun && l.Version != "", elem.Span(
+			vecty.Markup(
+				vecty.Class("version"),
+			),
+			vecty.Text(l.Version),
+		)),
+	)
+}
+
+func (l *Log) getVetFindings() vecty.MarkupOrChild {
+	if l.VetErrors == "" {
+		return nil
+	}
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("vet"),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("title"),
+			),
+			vecty.Text("Vet findings:"),
+		),
+		vecty.Text(l.VetErrors),
 	)
-	return out
 }
 
 func (l *Log) getStatusText() string {
@@ -96,6 +243,10 @@ func (l *Log) getStatusText() string {
 	return "Syntax OK"
 }
 
+func (l *Log) getCoverageText() string {
+	return "coverage: " + strconv.FormatFloat(l.Coverage.Percent, 'f', 1, 64) + "% of statements"
+}
+
 // ScrollToBottom scrolls log area to the bottom
 func (l *Log) ScrollToBottom() {
 	if l.node == nil {
@@ -118,13 +269,21 @@ func (l *Log) Render() vecty.ComponentOrHTML {
 		vecty.Markup(
 			vecty.Class("log"),
 		),
+		l.getVetFindings(),
 		elem.Div(l.getEvents()...),
+		l.getFuzzCrash(),
 		elem.Div(
 			vecty.Markup(
 				vecty.Class("status"),
 				vecty.MarkupIf(l.Error != "", vecty.Class("error")),
 			),
 			vecty.Text(l.getStatusText()),
+			vecty.If(l.Coverage != nil && !l.Running, elem.Span(
+				vecty.Markup(
+					vecty.Class("coverage"),
+				),
+				vecty.Text(l.getCoverageText()),
+			)),
 		),
 	)
 }
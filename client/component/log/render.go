@@ -0,0 +1,191 @@
+package log
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+)
+
+// Renderer renders a single line of the program output
+// (including the trailing newline, if any); it returns nil
+// if it doesn't handle the line
+type Renderer func(line string) vecty.MarkupOrChild
+
+var renderers []Renderer
+
+// RegisterRenderer adds the renderer to the list of output renderers.
+// Renderers are tried in the order they were registered;
+// lines that none of them handles are shown as plain text.
+func RegisterRenderer(r Renderer) {
+	renderers = append(renderers, r)
+}
+
+func init() {
+	RegisterRenderer(renderImage)
+	RegisterRenderer(renderANSI)
+}
+
+// renderOutput renders the program output line by line;
+// the HTML renderer is only used if HTMLOutput is set
+func (l *Log) renderOutput(text string) []vecty.MarkupOrChild {
+	renderers := renderers
+	if l.HTMLOutput {
+		renderers = append([]Renderer{renderHTML}, renderers...)
+	}
+
+	var out []vecty.MarkupOrChild
+	plain := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+
+		var html vecty.MarkupOrChild
+		for _, r := range renderers {
+			if html = r(line); html != nil {
+				break
+			}
+		}
+		if html == nil {
+			plain += line
+			continue
+		}
+
+		if plain != "" {
+			out = append(out, vecty.Text(plain))
+			plain = ""
+		}
+		out = append(out, html)
+	}
+	if plain != "" {
+		out = append(out, vecty.Text(plain))
+	}
+	return out
+}
+
+const (
+	imagePrefix = "IMAGE:"
+	htmlPrefix  = "HTML:"
+)
+
+var base64R = regexp.MustCompile(`^[A-Za-z0-9+/]+=*$`)
+
+// renderImage renders 'IMAGE:<base64-encoded PNG>' lines
+// (the same convention as on play.golang.org)
+func renderImage(line string) vecty.MarkupOrChild {
+	if !strings.HasPrefix(line, imagePrefix) {
+		return nil
+	}
+	data := strings.TrimSpace(line[len(imagePrefix):])
+	if !base64R.MatchString(data) {
+		return nil
+	}
+	return elem.Image(
+		vecty.Markup(
+			vecty.Class("image"),
+			vecty.Property("src", "data:image/png;base64,"+data),
+		),
+	)
+}
+
+// renderHTML renders 'HTML:<markup>' lines; the markup
+// must be on a single line, and is sanitized before rendering
+func renderHTML(line string) vecty.MarkupOrChild {
+	if !strings.HasPrefix(line, htmlPrefix) {
+		return nil
+	}
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("html"),
+			vecty.UnsafeHTML(sanitizeHTML(line[len(htmlPrefix):])),
+		),
+	)
+}
+
+const ansiEscape = "\x1b["
+
+var ansiSequenceR = regexp.MustCompile(`\x1b\[([\d;]*)([A-Za-z])`)
+
+// ansiState is the current text style set with ANSI SGR sequences
+type ansiState struct {
+	bold bool
+	fg   int
+	bg   int
+}
+
+func (s *ansiState) apply(params string) {
+	if params == "" {
+		params = "0"
+	}
+	for _, p := range strings.Split(params, ";") {
+		n, _ := strconv.Atoi(p)
+		switch {
+		case n == 0:
+			*s = ansiState{}
+		case n == 1:
+			s.bold = true
+		case n == 22:
+			s.bold = false
+		case n >= 30 && n <= 37, n >= 90 && n <= 97:
+			s.fg = n
+		case n == 39:
+			s.fg = 0
+		case n >= 40 && n <= 47, n >= 100 && n <= 107:
+			s.bg = n
+		case n == 49:
+			s.bg = 0
+		}
+	}
+}
+
+func (s *ansiState) classes() []string {
+	var out []string
+	if s.bold {
+		out = append(out, "ansi-bold")
+	}
+	if s.fg != 0 {
+		out = append(out, "ansi-"+strconv.Itoa(s.fg))
+	}
+	if s.bg != 0 {
+		out = append(out, "ansi-"+strconv.Itoa(s.bg))
+	}
+	return out
+}
+
+// renderANSI renders lines with ANSI color escape sequences;
+// unsupported sequences are removed. The style is reset
+// at the end of each line.
+func renderANSI(line string) vecty.MarkupOrChild {
+	if !strings.Contains(line, ansiEscape) {
+		return nil
+	}
+
+	var spans []vecty.MarkupOrChild
+	state := &ansiState{}
+	addSpan := func(text string) {
+		if text == "" {
+			return
+		}
+		spans = append(spans, elem.Span(
+			vecty.Markup(
+				vecty.Class(state.classes()...),
+			),
+			vecty.Text(text),
+		))
+	}
+
+	pos := 0
+	for _, m := range ansiSequenceR.FindAllStringSubmatchIndex(line, -1) {
+		addSpan(line[pos:m[0]])
+		pos = m[1]
+		if line[m[4]:m[5]] == "m" {
+			state.apply(line[m[2]:m[3]])
+		}
+	}
+	addSpan(line[pos:])
+
+	return elem.Span(spans...)
+}
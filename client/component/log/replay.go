@@ -0,0 +1,125 @@
+package log
+
+import (
+	"time"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// Replay reveals log events one by one according to their delays,
+// the same way the program output appeared when it was running
+type Replay struct {
+	// Shown is the number of events revealed so far
+	Shown  int
+	Paused bool
+
+	events   []*api.CompileEvent
+	timer    *time.Timer
+	onChange func()
+}
+
+// NewReplay creates a replay of the events;
+// onChange is called each time the state of the replay changes
+func NewReplay(events []*api.CompileEvent, onChange func()) *Replay {
+	return &Replay{
+		events:   events,
+		onChange: onChange,
+	}
+}
+
+// Done returns true if all the events have been revealed
+func (r *Replay) Done() bool {
+	return r.Shown >= len(r.events)
+}
+
+// Start starts (or resumes) the replay
+func (r *Replay) Start() {
+	r.Paused = false
+	r.scheduleNext()
+	r.onChange()
+}
+
+// Pause pauses the replay
+func (r *Replay) Pause() {
+	r.Paused = true
+	r.stopTimer()
+	r.onChange()
+}
+
+// Skip reveals all the remaining events at once
+func (r *Replay) Skip() {
+	r.stopTimer()
+	r.Shown = len(r.events)
+	r.onChange()
+}
+
+// Stop stops the replay without changing its state
+// (e.g. when the replayed events are no longer displayed)
+func (r *Replay) Stop() {
+	r.stopTimer()
+}
+
+func (r *Replay) stopTimer() {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+func (r *Replay) scheduleNext() {
+	r.stopTimer()
+	if r.Paused || r.Done() {
+		return
+	}
+
+	r.timer = time.AfterFunc(r.events[r.Shown].Delay, func() {
+		r.timer = nil
+		r.Shown++
+		r.scheduleNext()
+		r.onChange()
+	})
+}
+
+// getReplayControls renders the pause/resume and skip buttons
+// shown while the replay is in progress
+func (l *Log) getReplayControls() *vecty.HTML {
+	r := l.Replay
+
+	toggleText := "Pause"
+	toggle := r.Pause
+	if r.Paused {
+		toggleText = "Resume"
+		toggle = r.Start
+	}
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("replay"),
+		),
+		elem.Span(
+			vecty.Markup(
+				vecty.Class("title"),
+			),
+			vecty.Text("Playing in real time… "),
+		),
+		elem.Button(
+			vecty.Markup(
+				event.Click(func(e *vecty.Event) {
+					toggle()
+				}),
+			),
+			vecty.Text(toggleText),
+		),
+		elem.Button(
+			vecty.Markup(
+				event.Click(func(e *vecty.Event) {
+					r.Skip()
+				}),
+			),
+			vecty.Text("Skip"),
+		),
+	)
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"strings"
+
+	"github.com/gopherjs/gopherjs/js"
+	"github.com/iafan/goplayspace/client/js/document"
+)
+
+const (
+	elementNode = 1
+	textNode    = 3
+)
+
+// allowedTags are the HTML elements kept by sanitizeHTML
+var allowedTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "br": true, "code": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "em": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "i": true, "img": true, "kbd": true, "li": true,
+	"ol": true, "p": true, "pre": true, "s": true, "small": true,
+	"span": true, "strong": true, "sub": true, "sup": true,
+	"table": true, "tbody": true, "td": true, "tfoot": true, "th": true,
+	"thead": true, "tr": true, "u": true, "ul": true,
+}
+
+// allowedAttrs are the HTML attributes kept by sanitizeHTML;
+// 'href' and 'src' are additionally checked by isSafeURL.
+// Styles aren't allowed: CSS escapes make them hard to check,
+// and positioned elements could cover the page imitating the UI.
+var allowedAttrs = map[string]bool{
+	"align": true, "alt": true, "colspan": true, "height": true,
+	"href": true, "rowspan": true, "src": true,
+	"title": true, "width": true,
+}
+
+// sanitizeHTML removes everything except the basic formatting
+// from the markup: scripts, event handlers, forms, frames,
+// external resources and so on
+func sanitizeHTML(markup string) string {
+	// the content of a template element is inert:
+	// scripts don't run and images don't load
+	tpl := document.CreateElement("template")
+	tpl.Set("innerHTML", markup)
+	sanitizeNode(tpl.Get("content"))
+	return tpl.Get("innerHTML").String()
+}
+
+func sanitizeNode(node *js.Object) {
+	children := node.Get("childNodes")
+	for i := children.Length() - 1; i >= 0; i-- {
+		child := children.Index(i)
+		switch child.Get("nodeType").Int() {
+		case elementNode:
+			tag := strings.ToLower(child.Get("tagName").String())
+			if !allowedTags[tag] {
+				node.Call("removeChild", child)
+				continue
+			}
+			sanitizeAttrs(child, tag)
+			sanitizeNode(child)
+		case textNode:
+		default:
+			// comments, processing instructions etc.
+			node.Call("removeChild", child)
+		}
+	}
+}
+
+func sanitizeAttrs(el *js.Object, tag string) {
+	attrs := el.Get("attributes")
+	for i := attrs.Length() - 1; i >= 0; i-- {
+		name := strings.ToLower(attrs.Index(i).Get("name").String())
+		value := attrs.Index(i).Get("value").String()
+
+		ok := allowedAttrs[name]
+		if name == "href" || name == "src" {
+			ok = isSafeURL(tag, value)
+		}
+		if !ok {
+			el.Call("removeAttribute", name)
+		}
+	}
+
+	if tag == "a" {
+		el.Call("setAttribute", "target", "_blank")
+		el.Call("setAttribute", "rel", "noopener noreferrer")
+	}
+}
+
+// isSafeURL tells if the URL is allowed in the link
+// or image of the sanitized markup: links may only point
+// to web pages, and images may only use embedded data
+func isSafeURL(tag, url string) bool {
+	url = strings.ToLower(strings.TrimSpace(url))
+	switch tag {
+	case "a":
+		return strings.HasPrefix(url, "https://") ||
+			strings.HasPrefix(url, "http://")
+	case "img":
+		return strings.HasPrefix(url, "data:image/png;") ||
+			strings.HasPrefix(url, "data:image/gif;") ||
+			strings.HasPrefix(url, "data:image/jpeg;")
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+)
+
+var testStatusIcons = map[string]string{
+	"pass": "✓",
+	"fail": "✗",
+	"skip": "–",
+}
+
+// getTestResults renders the list of test results
+// (used instead of the raw output in test mode)
+func (l *Log) getTestResults() []vecty.MarkupOrChild {
+	out := make([]vecty.MarkupOrChild, 0, len(l.Tests)+1)
+	for _, t := range l.Tests {
+		out = append(out, l.getTestResult(t))
+	}
+	return append(out, l.getFinal())
+}
+
+func (l *Log) getTestResult(t *api.TestResult) *vecty.HTML {
+	// subtests are shown under their parent tests
+	name := t.Name
+	isSubtest := false
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+		isSubtest = true
+	}
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("test", t.Status),
+			vecty.MarkupIf(isSubtest, vecty.Class("subtest")),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("title"),
+				vecty.MarkupIf(l.OnTestSelect != nil,
+					vecty.Attribute("title", "Go to "+t.Name),
+					event.Click(func(e *vecty.Event) {
+						l.OnTestSelect(t.Name)
+					}),
+				),
+			),
+			elem.Span(
+				vecty.Markup(
+					vecty.Class("icon"),
+				),
+				vecty.Text(testStatusIcons[t.Status]),
+			),
+			elem.Span(
+				vecty.Markup(
+					vecty.Class("name"),
+				),
+				vecty.Text(name),
+			),
+			vecty.If(t.Kind != "benchmark", elem.Span(
+				vecty.Markup(
+					vecty.Class("elapsed"),
+				),
+				vecty.Text(strconv.FormatFloat(t.Elapsed, 'f', 2, 64)+"s"),
+			)),
+			l.getFuzzButton(t, isSubtest),
+		),
+		vecty.If(t.Output != "", elem.Div(
+			vecty.Markup(
+				vecty.Class("output"),
+			),
+			vecty.Text(t.Output),
+		)),
+	)
+}
@@ -0,0 +1,124 @@
+package profile
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// minFlameFraction is the smallest share of the total value
+// of the flame graph nodes that are shown
+const minFlameFraction = 0.005
+
+// flameNode is a function in the flame graph; calls of the same
+// function from the same stack are merged
+type flameNode struct {
+	name     string
+	file     string
+	value    int64
+	lines    map[int]int64
+	children []*flameNode
+	byName   map[string]*flameNode
+}
+
+func newFlameNode(name, file string) *flameNode {
+	return &flameNode{
+		name:   name,
+		file:   file,
+		lines:  make(map[int]int64),
+		byName: make(map[string]*flameNode),
+	}
+}
+
+// child returns the child node for the frame, adding it if needed
+func (n *flameNode) child(frame *api.ProfileFrame) *flameNode {
+	c := n.byName[frame.Func]
+	if c == nil {
+		c = newFlameNode(frame.Func, frame.File)
+		n.byName[frame.Func] = c
+		n.children = append(n.children, c)
+	}
+	return c
+}
+
+// hottestLine returns the line of the function
+// with the largest value
+func (n *flameNode) hottestLine() int {
+	line, max := 0, int64(-1)
+	for l, v := range n.lines {
+		if v > max || (v == max && l < line) {
+			line, max = l, v
+		}
+	}
+	return line
+}
+
+// buildFlameGraph merges the stacks of the samples into a tree
+func buildFlameGraph(data *api.ProfileData) *flameNode {
+	root := newFlameNode("all", "")
+	for _, s := range data.Samples {
+		root.value += s.Value
+		n := root
+		for _, i := range s.Stack {
+			frame := data.Frames[i]
+			n = n.child(frame)
+			n.value += s.Value
+			n.lines[frame.Line] += s.Value
+		}
+	}
+	return root
+}
+
+func (p *Pane) getFlameNode(n *flameNode, parentValue, total int64) *vecty.HTML {
+	sort.Slice(n.children, func(i, j int) bool {
+		return n.children[i].value > n.children[j].value
+	})
+
+	children := []vecty.MarkupOrChild{
+		vecty.Markup(
+			vecty.Class("children"),
+		),
+	}
+	for _, c := range n.children {
+		if float64(c.value) < float64(total)*minFlameFraction {
+			continue
+		}
+		children = append(children, p.getFlameNode(c, n.value, total))
+	}
+
+	width := strconv.FormatFloat(float64(n.value)*100/float64(parentValue), 'f', 3, 64) + "%"
+	title := n.name + " — " + FormatValue(p.data().Unit, n.value) + " (" + percent(n.value, total) + ")"
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("node"),
+			vecty.Style("width", width),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("frame"),
+				vecty.MarkupIf(isSnippetFile(n.file), vecty.Class("own")),
+				vecty.Attribute("title", title),
+				event.Click(func(e *vecty.Event) {
+					p.selectLine(n.file, n.hottestLine())
+				}),
+			),
+			vecty.Text(n.name),
+		),
+		elem.Div(children...),
+	)
+}
+
+func (p *Pane) getFlameGraph(data *api.ProfileData) *vecty.HTML {
+	root := buildFlameGraph(data)
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("flame"),
+		),
+		p.getFlameNode(root, root.value, root.value),
+	)
+}
@@ -0,0 +1,163 @@
+package profile
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// Profile kinds
+const (
+	CPU  = "cpu"
+	Heap = "heap"
+)
+
+// Pane contains the logic behind the profile pane
+// exposed on the application page under '.profile' class
+type Pane struct {
+	vecty.Core
+
+	Profile *api.Profile `vecty:"prop"`
+	Kind    string       `vecty:"prop"`
+	Running bool         `vecty:"prop"`
+
+	OnKindChange func(kind string)
+	OnSelect     func(file string, line int)
+}
+
+// Data returns the profile of the given kind
+func Data(p *api.Profile, kind string) *api.ProfileData {
+	if p == nil {
+		return nil
+	}
+	if kind == Heap {
+		return p.Heap
+	}
+	return p.CPU
+}
+
+// FormatValue returns the human-readable profile value,
+// e.g. '120ms' or '1.5 MB'
+func FormatValue(unit string, v int64) string {
+	f := float64(v)
+	switch unit {
+	case "nanoseconds":
+		switch {
+		case v >= 1e9:
+			return strconv.FormatFloat(f/1e9, 'f', 2, 64) + "s"
+		case v >= 1e6:
+			return strconv.FormatFloat(f/1e6, 'f', 0, 64) + "ms"
+		}
+		return strconv.FormatFloat(f/1e3, 'f', 0, 64) + "µs"
+	case "bytes":
+		switch {
+		case v >= 1<<20:
+			return strconv.FormatFloat(f/(1<<20), 'f', 1, 64) + " MB"
+		case v >= 1<<10:
+			return strconv.FormatFloat(f/(1<<10), 'f', 1, 64) + " KB"
+		}
+		return strconv.FormatInt(v, 10) + " B"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func percent(v, total int64) string {
+	if total == 0 {
+		return "0%"
+	}
+	return strconv.FormatFloat(float64(v)*100/float64(total), 'f', 1, 64) + "%"
+}
+
+// isSnippetFile tells if the file name from the profile
+// refers to a snippet file rather than to the Go runtime
+// or the standard library
+func isSnippetFile(file string) bool {
+	return file != "" && !path.IsAbs(file)
+}
+
+func (p *Pane) data() *api.ProfileData {
+	return Data(p.Profile, p.Kind)
+}
+
+func (p *Pane) selectLine(file string, line int) {
+	if p.OnSelect != nil && isSnippetFile(file) && line > 0 {
+		p.OnSelect(file, line)
+	}
+}
+
+func (p *Pane) getKindButton(kind, title string) *vecty.HTML {
+	return elem.Button(
+		vecty.Markup(
+			vecty.MarkupIf(p.Kind == kind, vecty.Class("active")),
+			vecty.Property("disabled", Data(p.Profile, kind) == nil),
+			event.Click(func(e *vecty.Event) {
+				if p.OnKindChange != nil {
+					p.OnKindChange(kind)
+				}
+			}),
+		),
+		vecty.Text(title),
+	)
+}
+
+func (p *Pane) getContent() vecty.MarkupOrChild {
+	message := func(text string) *vecty.HTML {
+		return elem.Div(
+			vecty.Markup(
+				vecty.Class("message"),
+			),
+			vecty.Text(text),
+		)
+	}
+
+	switch data := p.data(); {
+	case p.Running:
+		return message("Profiling…")
+	case p.Profile == nil:
+		return message("Press Profile to run the program with CPU and heap profiling.")
+	case data == nil:
+		return message("The profile is not available: the program must return from main (os.Exit skips writing the profile).")
+	case data.Total == 0:
+		return message("No samples: the program ran too fast to be profiled.")
+	default:
+		return elem.Div(
+			vecty.Markup(
+				vecty.Class("report"),
+			),
+			p.getFlameGraph(data),
+			p.getTopTable(data),
+		)
+	}
+}
+
+// Render implements the vecty.Component interface.
+func (p *Pane) Render() vecty.ComponentOrHTML {
+	status := ""
+	if data := p.data(); data != nil && !p.Running {
+		status = "Total: " + FormatValue(data.Unit, data.Total)
+	}
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("profile"),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("toolbar"),
+			),
+			p.getKindButton(CPU, "CPU"),
+			p.getKindButton(Heap, "Allocations"),
+			elem.Span(
+				vecty.Markup(
+					vecty.Class("status"),
+				),
+				vecty.Text(status),
+			),
+		),
+		p.getContent(),
+	)
+}
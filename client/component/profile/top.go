@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"sort"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+)
+
+// topSize is the number of functions in the top table
+const topSize = 20
+
+// topEntry is the total value of a function: flat is the value
+// of the function itself, and cum includes the functions it calls
+type topEntry struct {
+	*flameNode
+	flat int64
+	cum  int64
+}
+
+// buildTop returns the functions sorted by their flat value
+func buildTop(data *api.ProfileData) []*topEntry {
+	byName := make(map[string]*topEntry)
+	var out []*topEntry
+	for _, s := range data.Samples {
+		seen := make(map[string]bool)
+		for i, frameIndex := range s.Stack {
+			frame := data.Frames[frameIndex]
+			e := byName[frame.Func]
+			if e == nil {
+				e = &topEntry{flameNode: newFlameNode(frame.Func, frame.File)}
+				byName[frame.Func] = e
+				out = append(out, e)
+			}
+			e.lines[frame.Line] += s.Value
+
+			// recursive calls are only counted once
+			if !seen[frame.Func] {
+				e.cum += s.Value
+				seen[frame.Func] = true
+			}
+			if i == len(s.Stack)-1 {
+				e.flat += s.Value
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].flat != out[j].flat {
+			return out[i].flat > out[j].flat
+		}
+		return out[i].cum > out[j].cum
+	})
+	return out
+}
+
+func (p *Pane) getTopTable(data *api.ProfileData) *vecty.HTML {
+	rows := []vecty.MarkupOrChild{
+		elem.TableRow(
+			elem.TableHeader(vecty.Text("Flat")),
+			elem.TableHeader(vecty.Text("Flat%")),
+			elem.TableHeader(vecty.Text("Cum")),
+			elem.TableHeader(vecty.Text("Cum%")),
+			elem.TableHeader(vecty.Text("Function")),
+		),
+	}
+
+	top := buildTop(data)
+	if len(top) > topSize {
+		top = top[:topSize]
+	}
+	for _, e := range top {
+		e := e
+		rows = append(rows, elem.TableRow(
+			vecty.Markup(
+				vecty.MarkupIf(isSnippetFile(e.file), vecty.Class("own")),
+				event.Click(func(ev *vecty.Event) {
+					p.selectLine(e.file, e.hottestLine())
+				}),
+			),
+			elem.TableData(vecty.Text(FormatValue(data.Unit, e.flat))),
+			elem.TableData(vecty.Text(percent(e.flat, data.Total))),
+			elem.TableData(vecty.Text(FormatValue(data.Unit, e.cum))),
+			elem.TableData(vecty.Text(percent(e.cum, data.Total))),
+			elem.TableData(vecty.Text(e.name)),
+		))
+	}
+
+	return elem.Table(
+		vecty.Markup(
+			vecty.Class("top"),
+		),
+		elem.TableBody(rows...),
+	)
+}
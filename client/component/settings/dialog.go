@@ -18,10 +18,40 @@ type Dialog struct {
 	UseWebfont       bool   `vecty:"prop"`
 	HighlightingMode bool   `vecty:"prop"`
 	ShowSidebar      bool   `vecty:"prop"`
+	RealTimeReplay   bool   `vecty:"prop"`
+
+	// HTMLOutput tells to render 'HTML:' lines of the program output
+	HTMLOutput bool `vecty:"prop"`
+
+	// Build options
+	Race        bool   `vecty:"prop"`
+	BuildTags   string `vecty:"prop"`
+	BuildTarget string `vecty:"prop"`
+
+	// OptDiagnostics tells to show escape analysis
+	// and inlining decisions of the compiler in the editor
+	OptDiagnostics bool `vecty:"prop"`
 
 	OnChange func(d *Dialog)
 }
 
+// buildTargets are the GOOS/GOARCH values offered in the dialog;
+// the empty value is the server platform
+var buildTargets = []string{
+	"",
+	"linux/amd64",
+	"linux/arm64",
+	"linux/386",
+	"linux/arm",
+	"darwin/amd64",
+	"darwin/arm64",
+	"windows/amd64",
+	"windows/arm64",
+	"freebsd/amd64",
+	"js/wasm",
+	"wasip1/wasm",
+}
+
 /*
 func (d *Dialog) getDOMNode() *js.Object {
 	if d.node == nil {
@@ -61,6 +91,54 @@ func (d *Dialog) updateShowSidebar(e *vecty.Event) {
 	d.fireOnChangeEvent()
 }
 
+func (d *Dialog) updateRealTimeReplay(e *vecty.Event) {
+	d.RealTimeReplay = e.Target.Get("checked").Bool()
+	d.fireOnChangeEvent()
+}
+
+func (d *Dialog) updateHTMLOutput(e *vecty.Event) {
+	d.HTMLOutput = e.Target.Get("checked").Bool()
+	d.fireOnChangeEvent()
+}
+
+func (d *Dialog) updateRace(e *vecty.Event) {
+	d.Race = e.Target.Get("checked").Bool()
+	d.fireOnChangeEvent()
+}
+
+func (d *Dialog) updateOptDiagnostics(e *vecty.Event) {
+	d.OptDiagnostics = e.Target.Get("checked").Bool()
+	d.fireOnChangeEvent()
+}
+
+func (d *Dialog) updateBuildTags(e *vecty.Event) {
+	d.BuildTags = e.Target.Get("value").String()
+	d.fireOnChangeEvent()
+}
+
+func (d *Dialog) updateBuildTarget(e *vecty.Event) {
+	d.BuildTarget = e.Target.Get("value").String()
+	d.fireOnChangeEvent()
+}
+
+func (d *Dialog) getBuildTargetOptions() []vecty.MarkupOrChild {
+	out := make([]vecty.MarkupOrChild, len(buildTargets))
+	for i, target := range buildTargets {
+		title := target
+		if target == "" {
+			title = "Server platform (run)"
+		}
+		out[i] = elem.Option(
+			vecty.Markup(
+				vecty.Property("value", target),
+				vecty.Property("selected", d.BuildTarget == target),
+			),
+			vecty.Text(title),
+		)
+	}
+	return out
+}
+
 func (d *Dialog) fireOnChangeEvent() {
 	if d.OnChange != nil {
 		d.OnChange(d)
@@ -229,5 +307,95 @@ func (d *Dialog) Render() vecty.ComponentOrHTML {
 				vecty.Text("Show help sidebar"),
 			),
 		),
+		elem.Paragraph(
+			elem.Input(
+				vecty.Markup(
+					vecty.Property("id", "realtimereplay"),
+					vecty.Property("type", "checkbox"),
+					vecty.MarkupIf(d.RealTimeReplay, vecty.Property("checked", "true")),
+					event.Change(d.updateRealTimeReplay),
+				),
+			),
+			elem.Label(
+				vecty.Markup(
+					vecty.Attribute("for", "realtimereplay"),
+				),
+				vecty.Text("Play output in real time"),
+			),
+		),
+		elem.Paragraph(
+			elem.Input(
+				vecty.Markup(
+					vecty.Property("id", "htmloutput"),
+					vecty.Property("type", "checkbox"),
+					vecty.MarkupIf(d.HTMLOutput, vecty.Property("checked", "true")),
+					event.Change(d.updateHTMLOutput),
+				),
+			),
+			elem.Label(
+				vecty.Markup(
+					vecty.Attribute("for", "htmloutput"),
+				),
+				vecty.Text("Render HTML output"),
+			),
+		),
+		elem.Paragraph(
+			elem.Input(
+				vecty.Markup(
+					vecty.Property("id", "race"),
+					vecty.Property("type", "checkbox"),
+					vecty.MarkupIf(d.Race, vecty.Property("checked", "true")),
+					event.Change(d.updateRace),
+				),
+			),
+			elem.Label(
+				vecty.Markup(
+					vecty.Attribute("for", "race"),
+				),
+				vecty.Text("Race detector"),
+			),
+		),
+		elem.Paragraph(
+			elem.Input(
+				vecty.Markup(
+					vecty.Property("id", "optdiagnostics"),
+					vecty.Property("type", "checkbox"),
+					vecty.MarkupIf(d.OptDiagnostics, vecty.Property("checked", "true")),
+					event.Change(d.updateOptDiagnostics),
+				),
+			),
+			elem.Label(
+				vecty.Markup(
+					vecty.Attribute("for", "optdiagnostics"),
+				),
+				vecty.Text("Show escape analysis and inlining"),
+			),
+		),
+		elem.Paragraph(
+			elem.Div(
+				vecty.Text("Build tags:"),
+			),
+			elem.Input(
+				vecty.Markup(
+					vecty.Property("type", "text"),
+					vecty.Property("value", d.BuildTags),
+					vecty.Attribute("placeholder", "e.g. foo,bar"),
+					vecty.Attribute("spellcheck", "false"),
+					event.Change(d.updateBuildTags),
+				),
+			),
+		),
+		elem.Paragraph(
+			elem.Div(
+				vecty.Text("Target platform (other ones are only built):"),
+			),
+			elem.Select(
+				append([]vecty.MarkupOrChild{
+					vecty.Markup(
+						event.Change(d.updateBuildTarget),
+					),
+				}, d.getBuildTargetOptions()...)...,
+			),
+		),
 	)
 }
@@ -0,0 +1,74 @@
+package ssa
+
+import (
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+)
+
+// Pane contains the logic behind the SSA pane
+// exposed on the application page under '.ssa' class
+type Pane struct {
+	vecty.Core
+
+	// Func is the name of the shown function
+	Func    string `vecty:"prop"`
+	HTML    string `vecty:"prop"`
+	Error   string `vecty:"prop"`
+	Loading bool   `vecty:"prop"`
+
+	OnRefresh func()
+}
+
+func (p *Pane) refreshClick(e *vecty.Event) {
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// Render implements the vecty.Component interface.
+func (p *Pane) Render() vecty.ComponentOrHTML {
+	status := p.Func
+	if p.Loading {
+		status = "Compiling " + p.Func + "…"
+	}
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("ssa"),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("toolbar"),
+			),
+			elem.Button(
+				vecty.Markup(
+					vecty.Property("disabled", p.Loading),
+					vecty.Attribute("title", "Show the function under the caret"),
+					event.Click(p.refreshClick),
+				),
+				vecty.Text("Refresh"),
+			),
+			elem.Span(
+				vecty.Markup(
+					vecty.Class("status"),
+				),
+				vecty.Text(status),
+			),
+		),
+		vecty.If(p.Error != "", elem.Div(
+			vecty.Markup(
+				vecty.Class("error"),
+			),
+			vecty.Text(p.Error),
+		)),
+		// the page generated by the compiler has its own scripts;
+		// the sandbox keeps them away from the application
+		vecty.If(p.Error == "" && p.HTML != "", elem.InlineFrame(
+			vecty.Markup(
+				vecty.Attribute("sandbox", "allow-scripts"),
+				vecty.Property("srcdoc", p.HTML),
+			),
+		)),
+	)
+}
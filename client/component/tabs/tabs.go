@@ -0,0 +1,80 @@
+package tabs
+
+import (
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+)
+
+// Tab describes a single tab
+type Tab struct {
+	Title     string
+	Closable  bool
+	HasErrors bool
+}
+
+// Tabs contains the logic behind the file tab strip
+// exposed on the application page under '.tabs' class
+type Tabs struct {
+	vecty.Core
+
+	Tabs   []*Tab `vecty:"prop"`
+	Active int    `vecty:"prop"`
+
+	OnSelect func(i int)
+	OnClose  func(i int)
+	OnAdd    func()
+}
+
+func (t *Tabs) renderTab(i int, tab *Tab) *vecty.HTML {
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("tab"),
+			vecty.MarkupIf(i == t.Active, vecty.Class("active")),
+			vecty.MarkupIf(tab.HasErrors, vecty.Class("error")),
+			event.Click(func(e *vecty.Event) {
+				if t.OnSelect != nil && i != t.Active {
+					t.OnSelect(i)
+				}
+			}),
+		),
+		vecty.Text(tab.Title),
+		vecty.If(tab.Closable, elem.Span(
+			vecty.Markup(
+				vecty.Class("close"),
+				vecty.Attribute("title", "Remove file"),
+				event.Click(func(e *vecty.Event) {
+					e.Call("stopPropagation")
+					if t.OnClose != nil {
+						t.OnClose(i)
+					}
+				}),
+			),
+			vecty.Text("×"),
+		)),
+	)
+}
+
+// Render implements the vecty.Component interface.
+func (t *Tabs) Render() vecty.ComponentOrHTML {
+	items := make([]vecty.MarkupOrChild, 0, len(t.Tabs)+2)
+	items = append(items, vecty.Markup(
+		vecty.Class("tabs"),
+	))
+	for i, tab := range t.Tabs {
+		items = append(items, t.renderTab(i, tab))
+	}
+	items = append(items, elem.Div(
+		vecty.Markup(
+			vecty.Class("tab", "add"),
+			vecty.Attribute("title", "Add file"),
+			event.Click(func(e *vecty.Event) {
+				if t.OnAdd != nil {
+					t.OnAdd()
+				}
+			}),
+		),
+		vecty.Text("+"),
+	))
+	return elem.Div(items...)
+}
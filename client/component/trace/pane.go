@@ -0,0 +1,325 @@
+package trace
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gopherjs/vecty"
+	"github.com/gopherjs/vecty/elem"
+	"github.com/gopherjs/vecty/event"
+	"github.com/iafan/goplayspace/client/api"
+	"github.com/iafan/goplayspace/client/util"
+)
+
+// Pane contains the logic behind the trace pane
+// exposed on the application page under '.trace' class
+type Pane struct {
+	vecty.Core
+
+	Trace *api.Trace `vecty:"prop"`
+
+	// Traced is true if the last run recorded the trace
+	Traced  bool `vecty:"prop"`
+	Running bool `vecty:"prop"`
+
+	OnSelect func(file string, line int)
+
+	// zoom is the timeline width relative to the pane width
+	zoom float64
+
+	// hover is the span under the mouse pointer
+	hover *hoverSpan
+
+	// drawn is the state of the drawn timeline
+	drawn drawState
+}
+
+// row is a timeline row: the GC or a goroutine
+type row struct {
+	label string
+	title string
+	spans []*api.TraceSpan
+}
+
+// hoverSpan is the span under the mouse pointer
+type hoverSpan struct {
+	row  *row
+	span *api.TraceSpan
+}
+
+// rows returns the timeline rows, the GC first
+func (p *Pane) rows() []*row {
+	rows := []*row{{label: "GC", title: "Garbage collection", spans: p.Trace.GC}}
+	for _, g := range p.Trace.Goroutines {
+		label := "G" + strconv.FormatInt(g.ID, 10) + " " + g.Func
+		rows = append(rows, &row{label: label, title: label, spans: g.Spans})
+	}
+	return rows
+}
+
+// formatDuration returns the human-readable duration,
+// e.g. '120ms' or '1.5µs'
+func formatDuration(ns int64) string {
+	f := float64(ns)
+	unit := "ns"
+	switch {
+	case ns >= 1e9:
+		f, unit = f/1e9, "s"
+	case ns >= 1e6:
+		f, unit = f/1e6, "ms"
+	case ns >= 1e3:
+		f, unit = f/1e3, "µs"
+	}
+
+	prec := 2
+	switch {
+	case f >= 100:
+		prec = 0
+	case f >= 10:
+		prec = 1
+	}
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	if prec > 0 {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	return s + unit
+}
+
+// describe returns the details of the span shown on hover
+func (h *hoverSpan) describe() string {
+	s := h.span
+	if h.row.label == "GC" {
+		return s.Reason + ": " + formatDuration(s.End-s.Start)
+	}
+
+	out := h.row.label + ": " + s.State
+	if s.Reason != "" {
+		out += " (" + s.Reason + ")"
+	}
+	out += " for " + formatDuration(s.End-s.Start) + " at " + formatDuration(s.Start)
+	if s.File != "" {
+		out += ", " + s.File + ":" + strconv.Itoa(s.Line)
+	}
+	return out
+}
+
+func (p *Pane) setHover(h *hoverSpan) {
+	if h == nil && p.hover == nil {
+		return
+	}
+	if h != nil && p.hover != nil && h.span == p.hover.span {
+		return
+	}
+	p.hover = h
+	vecty.Rerender(p)
+}
+
+func (p *Pane) onMouseMove(e *vecty.Event) {
+	p.setHover(p.spanAt(e.Get("offsetX").Float(), e.Get("offsetY").Float()))
+}
+
+func (p *Pane) onMouseLeave(e *vecty.Event) {
+	p.setHover(nil)
+}
+
+func (p *Pane) onClick(e *vecty.Event) {
+	h := p.spanAt(e.Get("offsetX").Float(), e.Get("offsetY").Float())
+	if h != nil && h.span.File != "" && p.OnSelect != nil {
+		p.OnSelect(h.span.File, h.span.Line)
+	}
+}
+
+// onWheel zooms the timeline in and out around the mouse pointer
+// with Ctrl (or Cmd) pressed; otherwise, the timeline is scrolled
+func (p *Pane) onWheel(e *vecty.Event) {
+	if !e.Get("ctrlKey").Bool() && !e.Get("metaKey").Bool() {
+		return
+	}
+	e.Call("preventDefault")
+
+	zoom := p.zoom * zoomStep
+	if e.Get("deltaY").Float() > 0 {
+		zoom = p.zoom / zoomStep
+	}
+	p.setZoom(zoom, e.Get("offsetX").Float()-timelineNode().Get("scrollLeft").Float())
+}
+
+func (p *Pane) zoomInClick(e *vecty.Event) {
+	p.setZoom(p.zoom*zoomStep, viewportWidth()/2)
+}
+
+func (p *Pane) zoomOutClick(e *vecty.Event) {
+	p.setZoom(p.zoom/zoomStep, viewportWidth()/2)
+}
+
+func (p *Pane) fitClick(e *vecty.Event) {
+	p.setZoom(1, 0)
+}
+
+func (p *Pane) getToolbar() *vecty.HTML {
+	status := ""
+	if p.Trace != nil && !p.Running {
+		status = "Duration: " + formatDuration(p.Trace.Duration)
+		if p.Trace.Truncated {
+			status += " (truncated)"
+		}
+	}
+	noTimeline := p.Trace == nil || p.Running
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("toolbar"),
+		),
+		elem.Button(
+			vecty.Markup(
+				vecty.Property("disabled", noTimeline),
+				vecty.Attribute("title", "Zoom out (Ctrl+wheel)"),
+				event.Click(p.zoomOutClick),
+			),
+			vecty.Text("−"),
+		),
+		elem.Button(
+			vecty.Markup(
+				vecty.Property("disabled", noTimeline),
+				vecty.Attribute("title", "Zoom in (Ctrl+wheel)"),
+				event.Click(p.zoomInClick),
+			),
+			vecty.Text("+"),
+		),
+		elem.Button(
+			vecty.Markup(
+				vecty.Property("disabled", noTimeline),
+				vecty.Attribute("title", "Fit the trace into the pane"),
+				event.Click(p.fitClick),
+			),
+			vecty.Text("Fit"),
+		),
+		elem.Span(
+			vecty.Markup(
+				vecty.Class("status"),
+			),
+			vecty.Text(status),
+		),
+	)
+}
+
+func (p *Pane) getLegend() *vecty.HTML {
+	items := []vecty.MarkupOrChild{
+		vecty.Markup(
+			vecty.Class("legend"),
+		),
+	}
+	for _, c := range categories {
+		items = append(items, elem.Span(
+			elem.Span(
+				vecty.Markup(
+					vecty.Class("swatch"),
+					vecty.Style("background", c.color),
+				),
+			),
+			vecty.Text(c.name),
+		))
+	}
+	return elem.Div(items...)
+}
+
+func (p *Pane) getTimeline() *vecty.HTML {
+	labels := []vecty.MarkupOrChild{
+		vecty.Markup(
+			vecty.Class("labels"),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("axis"),
+			),
+		),
+	}
+	for _, r := range p.rows() {
+		labels = append(labels, elem.Div(
+			vecty.Markup(
+				vecty.Class("row"),
+				vecty.Attribute("title", r.title),
+			),
+			vecty.Text(r.label),
+		))
+	}
+
+	details := "Hover over the timeline for details, click a blocked goroutine to select its line"
+	if p.hover != nil {
+		details = p.hover.describe()
+	}
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("report"),
+		),
+		p.getLegend(),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("timeline"),
+			),
+			elem.Div(labels...),
+			elem.Div(
+				vecty.Markup(
+					vecty.Class("chart"),
+				),
+				elem.Canvas(
+					vecty.Markup(
+						vecty.MarkupIf(p.hover != nil && p.hover.span.File != "", vecty.Class("selectable")),
+						event.MouseMove(p.onMouseMove),
+						event.MouseLeave(p.onMouseLeave),
+						event.Click(p.onClick),
+						event.Wheel(p.onWheel),
+					),
+				),
+			),
+		),
+		elem.Div(
+			vecty.Markup(
+				vecty.Class("details"),
+			),
+			vecty.Text(details),
+		),
+	)
+}
+
+func (p *Pane) getContent() vecty.MarkupOrChild {
+	message := func(text string) *vecty.HTML {
+		return elem.Div(
+			vecty.Markup(
+				vecty.Class("message"),
+			),
+			vecty.Text(text),
+		)
+	}
+
+	switch {
+	case p.Running:
+		return message("Tracing…")
+	case p.Trace == nil && !p.Traced:
+		return message("Press Trace to run the program with the execution tracer.")
+	case p.Trace == nil:
+		return message("The trace is not available: the program must return from main (os.Exit skips writing the trace).")
+	default:
+		return p.getTimeline()
+	}
+}
+
+// Render implements the vecty.Component interface.
+func (p *Pane) Render() vecty.ComponentOrHTML {
+	if p.Trace == nil || p.Running {
+		// the timeline is drawn anew when it's shown again
+		p.drawn = drawState{}
+		p.hover = nil
+	} else {
+		util.Schedule(p.draw)
+	}
+
+	return elem.Div(
+		vecty.Markup(
+			vecty.Class("trace"),
+		),
+		p.getToolbar(),
+		p.getContent(),
+	)
+}
@@ -0,0 +1,241 @@
+package trace
+
+import (
+	"math"
+	"strings"
+
+	"github.com/gopherjs/gopherjs/js"
+	"github.com/iafan/goplayspace/client/api"
+	"github.com/iafan/goplayspace/client/js/canvas"
+	"github.com/iafan/goplayspace/client/js/document"
+	"github.com/iafan/goplayspace/client/js/window"
+)
+
+const (
+	// axisHeight and rowHeight are the heights of the time axis
+	// and of the rows in px; they match the '.trace .labels' CSS styles
+	axisHeight = 20
+	rowHeight  = 18
+
+	// maxCanvasWidth is the largest timeline width in px
+	// (browsers limit the canvas size)
+	maxCanvasWidth = 30000
+
+	// minTickSpacing is the smallest distance between
+	// the time axis ticks in px
+	minTickSpacing = 80
+
+	// zoomStep is the zoom factor of the zoom buttons
+	// and of a single mouse wheel step
+	zoomStep = 1.5
+
+	// hoverTolerance is the distance in px from the spans
+	// that are too narrow to point at exactly
+	hoverTolerance = 2
+)
+
+// category is a kind of spans drawn with the same color
+type category struct {
+	name  string
+	color string
+
+	// full is true for the spans that take the full row height
+	full bool
+}
+
+var (
+	runningCategory  = &category{"Running", "#3a3", true}
+	runnableCategory = &category{"Runnable", "#bbb", false}
+	chanCategory     = &category{"Channel", "#49e", false}
+	syncCategory     = &category{"Sync", "#f90", false}
+	sleepCategory    = &category{"Sleep", "#c9c", false}
+	waitingCategory  = &category{"Other wait", "#888", false}
+	syscallCategory  = &category{"Syscall", "#a7d", true}
+	gcCategory       = &category{"GC", "rgba(255, 153, 0, 0.4)", false}
+	stwCategory      = &category{"Stop-the-world", "#d33", true}
+)
+
+// categories are listed in the legend
+var categories = []*category{
+	runningCategory,
+	runnableCategory,
+	chanCategory,
+	syncCategory,
+	sleepCategory,
+	waitingCategory,
+	syscallCategory,
+	gcCategory,
+	stwCategory,
+}
+
+// spanCategory returns the category of the span; waiting goroutines
+// are told apart by the reason they are blocked
+func spanCategory(s *api.TraceSpan) *category {
+	switch s.State {
+	case "running":
+		return runningCategory
+	case "runnable":
+		return runnableCategory
+	case "syscall":
+		return syscallCategory
+	case "gc":
+		return gcCategory
+	case "stw":
+		return stwCategory
+	}
+
+	switch {
+	case strings.HasPrefix(s.Reason, "chan ") || strings.HasPrefix(s.Reason, "select"):
+		return chanCategory
+	case strings.HasPrefix(s.Reason, "sync"):
+		return syncCategory
+	case s.Reason == "sleep":
+		return sleepCategory
+	}
+	return waitingCategory
+}
+
+// drawState is the state the timeline was drawn with
+type drawState struct {
+	trace *api.Trace
+	width float64
+}
+
+func timelineNode() *js.Object {
+	return document.QuerySelector(".trace .timeline")
+}
+
+// viewportWidth returns the width of the visible part of the timeline
+func viewportWidth() float64 {
+	timeline := timelineNode()
+	labels := document.QuerySelector(".trace .labels")
+	if timeline == nil || labels == nil {
+		return 0
+	}
+	return timeline.Get("clientWidth").Float() - labels.Get("offsetWidth").Float()
+}
+
+// maxZoom returns the zoom that makes the timeline as wide as possible
+func maxZoom() float64 {
+	if w := viewportWidth(); w > 0 {
+		return math.Max(maxCanvasWidth/w, 1)
+	}
+	return 1
+}
+
+// setZoom zooms the timeline, keeping the time at anchor
+// (the distance from the left side of the viewport) in place
+func (p *Pane) setZoom(zoom, anchor float64) {
+	timeline := timelineNode()
+	if timeline == nil || p.drawn.width == 0 {
+		return
+	}
+	pos := (timeline.Get("scrollLeft").Float() + anchor) / p.drawn.width
+
+	p.zoom = math.Min(math.Max(zoom, 1), maxZoom())
+	p.draw()
+	timeline.Set("scrollLeft", pos*p.drawn.width-anchor)
+}
+
+// tickStep returns the time between the time axis ticks:
+// 1, 2 or 5 multiplied by a power of 10 nanoseconds
+func tickStep(duration int64, width float64) int64 {
+	min := float64(duration) * minTickSpacing / width
+	for step := int64(1); ; step *= 10 {
+		for _, m := range []int64{1, 2, 5} {
+			if float64(step*m) >= min {
+				return step * m
+			}
+		}
+	}
+}
+
+// draw draws the timeline unless it's already drawn
+func (p *Pane) draw() {
+	node := document.QuerySelector(".trace canvas")
+	if node == nil || p.Trace == nil {
+		return
+	}
+
+	if p.drawn.trace != p.Trace {
+		p.zoom = 1
+	}
+	if p.zoom == 0 {
+		p.zoom = 1
+	}
+	width := math.Min(math.Floor(viewportWidth()*p.zoom), maxCanvasWidth)
+	state := drawState{p.Trace, width}
+	if state == p.drawn || width <= 0 {
+		return
+	}
+	p.drawn = state
+
+	rows := p.rows()
+	height := float64(axisHeight + rowHeight*len(rows))
+	c := &canvas.Canvas{node}
+	c.SetSize(width, height)
+	ctx := c.GetContext2D()
+	ctx.ClearRect(0, 0, width, height)
+
+	duration := p.Trace.Duration
+	if duration <= 0 {
+		return
+	}
+	scale := width / float64(duration)
+
+	// the time axis uses the text color of the pane
+	ctx.SetFillStyle(window.GetComputedStyle(node).Get("color").String())
+	ctx.SetFont("10px sans-serif")
+	step := tickStep(duration, width)
+	for t := int64(0); t <= duration; t += step {
+		x := math.Floor(float64(t) * scale)
+		ctx.FillRect(x, axisHeight-5, 1, 5)
+		ctx.FillText(formatDuration(t), x+3, axisHeight-7)
+	}
+
+	for i, r := range rows {
+		y := float64(axisHeight + i*rowHeight)
+		for _, s := range r.spans {
+			c := spanCategory(s)
+			top, h := 5.0, rowHeight-10.0
+			if c.full {
+				top, h = 2, rowHeight-4
+			}
+			x := float64(s.Start) * scale
+			ctx.SetFillStyle(c.color)
+			ctx.FillRect(x, y+top, math.Max(float64(s.End-s.Start)*scale, 1), h)
+		}
+	}
+}
+
+// spanAt returns the span at the canvas point; the spans
+// that contain the point are preferred to the nearby ones,
+// and the narrow ones (e.g. pauses within GC) to the wide ones
+func (p *Pane) spanAt(x, y float64) *hoverSpan {
+	if p.Trace == nil || p.drawn.width == 0 || y < axisHeight {
+		return nil
+	}
+	rows := p.rows()
+	i := int((y - axisHeight) / rowHeight)
+	if i >= len(rows) {
+		return nil
+	}
+
+	scale := p.drawn.width / float64(p.Trace.Duration)
+	t := x / scale
+	var best *api.TraceSpan
+	bestDist := math.Inf(1)
+	for _, s := range rows[i].spans {
+		dist := math.Max(math.Max(float64(s.Start)-t, t-float64(s.End)), 0) * scale
+		if dist > hoverTolerance {
+			continue
+		}
+		if dist < bestDist || (dist == bestDist && s.End-s.Start < best.End-best.Start) {
+			best, bestDist = s, dist
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &hoverSpan{rows[i], best}
+}
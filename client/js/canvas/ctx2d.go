@@ -14,6 +14,10 @@ func (ctx *CanvasRenderingContext2D) SetFillStyle(style interface{}) {
 	ctx.Set("fillStyle", style)
 }
 
+func (ctx *CanvasRenderingContext2D) SetFont(font string) {
+	ctx.Set("font", font)
+}
+
 func (ctx *CanvasRenderingContext2D) SetLineWidth(w float64) {
 	ctx.Set("lineWidth", w)
 }
@@ -36,6 +40,10 @@ func (ctx *CanvasRenderingContext2D) FillRect(x, y, w, h float64) {
 	ctx.Call("fillRect", x, y, w, h)
 }
 
+func (ctx *CanvasRenderingContext2D) FillText(text string, x, y float64) {
+	ctx.Call("fillText", text, x, y)
+}
+
 func (ctx *CanvasRenderingContext2D) Translate(x, y float64) {
 	ctx.Call("translate", x, y)
 }
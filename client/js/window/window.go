@@ -16,3 +16,28 @@ func RemoveEventListener(params ...interface{}) {
 func RequestAnimationFrame(callback interface{}) {
 	js.Global.Get("window").Call("requestAnimationFrame", callback)
 }
+
+// Prompt is a wrapper for window.prompt; it returns false
+// if the dialog was cancelled
+func Prompt(message, def string) (string, bool) {
+	v := js.Global.Get("window").Call("prompt", message, def)
+	if v == nil {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// Confirm is a wrapper for window.confirm
+func Confirm(message string) bool {
+	return js.Global.Get("window").Call("confirm", message).Bool()
+}
+
+// Alert is a wrapper for window.alert
+func Alert(message string) {
+	js.Global.Get("window").Call("alert", message)
+}
+
+// GetComputedStyle is a wrapper for window.getComputedStyle
+func GetComputedStyle(el *js.Object) *js.Object {
+	return js.Global.Get("window").Call("getComputedStyle", el)
+}
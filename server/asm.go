@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AsmRequest is the /api/v2/asm request payload
+type AsmRequest struct {
+	Body string
+
+	// Version is the Go version label;
+	// the default version is used if it is empty
+	Version string
+
+	// GOARCH is the target architecture;
+	// the server one is used if it is empty
+	GOARCH string `json:",omitempty"`
+}
+
+// AsmResponse is the /api/v2/asm response payload
+type AsmResponse struct {
+	// Errors contains the build errors, if any
+	Errors    string
+	Functions []*AsmFunction
+}
+
+// AsmFunction is the assembly of a single function
+type AsmFunction struct {
+	Name         string
+	Instructions []*AsmInstruction
+}
+
+// AsmInstruction is a single instruction and the source line
+// it was generated for
+type AsmInstruction struct {
+	// File is the snippet file name (e.g. 'main.go'),
+	// or '<autogenerated>' for generated code
+	File string
+	Line int
+	Text string
+}
+
+// assembler is implemented by backends that can show
+// the assembly generated for the source code
+type assembler interface {
+	Assemble(ctx context.Context, body, goarch string) (*AsmResponse, error)
+}
+
+var (
+	// asmFuncR matches function headers of the compiler -S output, e.g.
+	// 'main.main STEXT size=82 args=0x0 locals=0x40 funcid=0x0 align=0x0'
+	asmFuncR = regexp.MustCompile(`^(\S+) STEXT\b`)
+
+	// asmInstrR matches instructions of the compiler -S output, e.g.
+	// '	0x0004 00004 (/tmp/x/main.go:5)	CMPQ	SP, 16(R14)'
+	asmInstrR = regexp.MustCompile(`^\t0x[0-9a-f]+ \d+ \((.+):(\d+)\)\t(.*)$`)
+)
+
+// asmPseudoInstructions are omitted from the output,
+// since they carry no code
+var asmPseudoInstructions = []string{"PCDATA", "FUNCDATA"}
+
+// asmHandler implements the /api/v2/asm API
+func asmHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &AsmRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxSnippetSize)).Decode(req); err != nil {
+		http.Error(w, "Failed to decode request data", http.StatusBadRequest)
+		return
+	}
+	if !platformR.MatchString(req.GOARCH) {
+		http.Error(w, "Invalid GOARCH", http.StatusBadRequest)
+		return
+	}
+
+	version, b, err := backendFor(req.Version)
+	if err != nil {
+		writeCompileError(w, err)
+		return
+	}
+	asm, ok := b.(assembler)
+	if !ok {
+		writeCompileError(w, errAsmNotSupported)
+		return
+	}
+	req.Version = version
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		http.Error(w, "Failed to encode data", http.StatusInternalServerError)
+		return
+	}
+	key := cacheKey("asm:" + string(reqBytes))
+
+	writeAnalysisResult(w, r, key, func(ctx context.Context) (interface{}, bool, error) {
+		resp, err := asm.Assemble(ctx, req.Body, req.GOARCH)
+		if err != nil {
+			return nil, false, err
+		}
+		return resp, resp.Errors != timeoutErrorMessage, nil
+	})
+}
+
+// Assemble implements the assembler interface
+func (b *localBackend) Assemble(ctx context.Context, body, goarch string) (*AsmResponse, error) {
+	dir, err := b.prepare(body, progFile)
+	if err == errInvalidFileName {
+		return &AsmResponse{Errors: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+
+	var env []string
+	if goarch != "" {
+		env = append(env, "GOARCH="+goarch)
+	}
+
+	// the compiler output is replayed for cached builds,
+	// so the assembly is printed even if nothing is rebuilt
+	out, err := b.goCommand(ctx, dir, env, "build", "-gcflags=./...=-S", "-o", os.DevNull, "./...")
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return &AsmResponse{Errors: timeoutErrorMessage}, nil
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+		return &AsmResponse{Errors: out}, nil
+	}
+
+	return &AsmResponse{Functions: parseAsm(out, dir)}, nil
+}
+
+// parseAsm parses the compiler -S output; file paths are made
+// relative to the snippet directory
+func parseAsm(out, dir string) []*AsmFunction {
+	functions := make([]*AsmFunction, 0)
+	var fn *AsmFunction
+
+	prefix := filepath.ToSlash(dir) + "/"
+	s := bufio.NewScanner(strings.NewReader(out))
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		line := s.Text()
+
+		if m := asmFuncR.FindStringSubmatch(line); m != nil {
+			fn = &AsmFunction{Name: m[1]}
+			functions = append(functions, fn)
+			continue
+		}
+
+		m := asmInstrR.FindStringSubmatch(line)
+		if m == nil || fn == nil || isAsmPseudoInstruction(m[3]) {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		fn.Instructions = append(fn.Instructions, &AsmInstruction{
+			File: strings.TrimPrefix(filepath.ToSlash(m[1]), prefix),
+			Line: n,
+			Text: m[3],
+		})
+	}
+	return functions
+}
+
+func isAsmPseudoInstruction(text string) bool {
+	for _, op := range asmPseudoInstructions {
+		if strings.HasPrefix(text, op+"\t") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+)
+
+var (
+	errInputsNotSupported       = errors.New("Stdin, arguments and environment variables are not supported by this Go version")
+	errBuildOptionsNotSupported = errors.New("Race detector, build tags and GOOS/GOARCH are not supported by this Go version")
+	errAsmNotSupported          = errors.New("Assembly output is not supported by this Go version")
+	errDiagnosticsNotSupported  = errors.New("Optimization diagnostics are not supported by this Go version")
+	errSSANotSupported          = errors.New("SSA output is not supported by this Go version")
+	errProfileNotSupported      = errors.New("Profiling is not supported by this Go version")
+	errTraceNotSupported        = errors.New("Execution tracing is not supported by this Go version")
+	errFuzzNotSupported         = errors.New("Fuzzing is not supported by this Go version")
+)
+
+// isNotSupported returns true if the error is about a feature
+// that isn't supported by the backend
+func isNotSupported(err error) bool {
+	return err == errInputsNotSupported ||
+		err == errBuildOptionsNotSupported ||
+		err == errAsmNotSupported ||
+		err == errDiagnosticsNotSupported ||
+		err == errSSANotSupported ||
+		err == errProfileNotSupported ||
+		err == errTraceNotSupported ||
+		err == errFuzzNotSupported
+}
+
+// Backend is the interface implemented by code execution backends.
+// Backends return responses in the play.golang.org format,
+// so the client doesn't need to know which backend is in use.
+type Backend interface {
+	// Format formats the source code; if imports is true,
+	// it also runs goimports on it
+	Format(ctx context.Context, body string, imports bool) (*FmtResponse, error)
+
+	// Compile builds and runs the source code; if opts.Vet is set,
+	// it also runs go vet on it (like play.golang.org does with 'withVet').
+	// Canceling the context stops the program.
+	Compile(ctx context.Context, body string, opts *RunOptions) (*CompileResponse, error)
+}
+
+// BuildOptions contains the build options for Backend.Compile
+type BuildOptions struct {
+	// Race tells to build the program with the race detector
+	Race bool
+
+	// Tags are the additional build tags
+	Tags []string
+
+	// GOOS and GOARCH are the target platform; programs built
+	// for other platforms than the server one are not run
+	GOOS   string
+	GOARCH string
+}
+
+// isSet returns true if any of the options differs from the default
+func (o *BuildOptions) isSet() bool {
+	return o.Race || len(o.Tags) > 0 || o.GOOS != "" || o.GOARCH != ""
+}
+
+// isCrossCompile returns true if the target platform
+// differs from the server one
+func (o *BuildOptions) isCrossCompile() bool {
+	return (o.GOOS != "" && o.GOOS != runtime.GOOS) ||
+		(o.GOARCH != "" && o.GOARCH != runtime.GOARCH)
+}
+
+// env returns the environment variables for the go command
+func (o *BuildOptions) env() []string {
+	var out []string
+	if o.GOOS != "" {
+		out = append(out, "GOOS="+o.GOOS)
+	}
+	if o.GOARCH != "" {
+		out = append(out, "GOARCH="+o.GOARCH)
+	}
+	return out
+}
+
+// flags returns the build flags for the go command
+func (o *BuildOptions) flags() []string {
+	var out []string
+	if o.Race {
+		out = append(out, "-race")
+	}
+	if len(o.Tags) > 0 {
+		out = append(out, "-tags", strings.Join(o.Tags, ","))
+	}
+	return out
+}
+
+// RunOptions contains the options for Backend.Compile
+type RunOptions struct {
+	BuildOptions
+
+	// Test tells to run the code with 'go test'
+	Test bool
+
+	// Vet tells to run go vet on the code before running it;
+	// the findings are reported in the VetErrors and VetOK fields
+	// of the response
+	Vet bool
+
+	// Stdin, Args and Env are the program standard input,
+	// command-line arguments and additional environment
+	// variables (as KEY=VALUE pairs)
+	Stdin string
+	Args  []string
+	Env   []string
+
+	// Profile tells to collect the CPU and heap profiles of the run
+	Profile bool
+
+	// Trace tells to record the execution trace of the run
+	Trace bool
+
+	// Fuzz is the name of the fuzz test to fuzz in test mode
+	// (instead of running the tests)
+	Fuzz string
+
+	// OnEvent, if not nil, is called for each chunk
+	// of the program output as soon as it is produced
+	OnEvent func(evt *CompileEvent)
+
+	// OnFuzzProgress, if not nil, is called for each
+	// progress report of the fuzzing session
+	OnFuzzProgress func(p *FuzzProgress)
+}
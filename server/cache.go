@@ -0,0 +1,237 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cacheHeader is the response header that tells the client
+// whether the /compile response was served from cache
+const cacheHeader = "X-Compile-Cache"
+
+// how often compileCache removes expired entries from disk
+const cacheSweepInterval = 10 * time.Minute
+
+// compileCache stores final /compile responses keyed by the hash
+// of the submitted source code. Recently used entries are kept in memory
+// (up to MaxSize bytes); if Dir is set, entries are also saved to disk
+// (up to MaxDiskSize bytes) and survive server restarts.
+// Entries expire after TTL.
+type compileCache struct {
+	MaxSize     int
+	MaxDiskSize int64
+	TTL         time.Duration
+	Dir         string
+
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+
+	// diskSize is the size of the entries on disk
+	// as of the last sweep, plus the ones saved since
+	diskSize int64
+
+	// sweepc wakes up sweepLoop when MaxDiskSize is exceeded
+	sweepc chan struct{}
+}
+
+type cacheEntry struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+// cache is the compileCache instance used by compileHandler;
+// nil if caching is disabled
+var cache *compileCache
+
+// cacheLocalRuns tells to cache the results of the programs
+// run by the local backends (see cacheResult)
+var cacheLocalRuns bool
+
+// newCompileCache returns the cache; if dir is set,
+// it also starts removing expired entries from disk
+func newCompileCache(maxSize int, maxDiskSize int64, ttl time.Duration, dir string) *compileCache {
+	c := &compileCache{
+		MaxSize:     maxSize,
+		MaxDiskSize: maxDiskSize,
+		TTL:         ttl,
+		Dir:         dir,
+		lru:         list.New(),
+		entries:     make(map[string]*list.Element),
+	}
+	if dir != "" {
+		c.sweepc = make(chan struct{}, 1)
+		go c.sweepLoop()
+	}
+	return c
+}
+
+// cacheKey returns the cache key for the source code
+func cacheKey(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *compileCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Get returns the cached response, if any
+func (c *compileCache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(el)
+			return entry.data, true
+		}
+		c.remove(el)
+	}
+
+	if c.Dir == "" {
+		return nil, false
+	}
+
+	fi, err := os.Stat(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	expires := fi.ModTime().Add(c.TTL)
+	if time.Now().After(expires) {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	c.add(key, data, expires)
+	return data, true
+}
+
+// Put saves the response to cache
+func (c *compileCache) Put(key string, data []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.add(key, data, time.Now().Add(c.TTL))
+
+	if c.Dir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		log.Printf("Failed to create cache directory: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(c.path(key), data, 0644); err != nil {
+		log.Printf("Failed to save cache entry: %v", err)
+		return
+	}
+	c.diskSize += int64(len(data))
+	if c.diskSize > c.MaxDiskSize {
+		select {
+		case c.sweepc <- struct{}{}:
+		default: // the sweep is already pending
+		}
+	}
+}
+
+// sweepLoop sweeps the disk entries every cacheSweepInterval,
+// or as soon as they take more than MaxDiskSize
+func (c *compileCache) sweepLoop() {
+	ticker := time.NewTicker(cacheSweepInterval)
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.sweepc:
+		}
+		c.sweep(time.Now())
+	}
+}
+
+// sweep removes expired entries from disk, and then the oldest ones
+// while the entries take more than MaxDiskSize; the requests are not
+// blocked meanwhile, so the entries saved during the sweep are only
+// counted by the next one
+func (c *compileCache) sweep(now time.Time) {
+	files, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read cache directory: %v", err)
+		}
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	var size int64
+	var kept []os.FileInfo
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+		if now.After(fi.ModTime().Add(c.TTL)) {
+			os.Remove(filepath.Join(c.Dir, fi.Name()))
+			continue
+		}
+		kept = append(kept, fi)
+		size += fi.Size()
+	}
+
+	for _, fi := range kept {
+		if size <= c.MaxDiskSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.Dir, fi.Name())); err == nil {
+			size -= fi.Size()
+		}
+	}
+
+	c.mu.Lock()
+	c.diskSize = size
+	c.mu.Unlock()
+}
+
+func (c *compileCache) add(key string, data []byte, expires time.Time) {
+	if len(data) > c.MaxSize {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key, data, expires})
+	c.size += len(data)
+
+	for c.size > c.MaxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *compileCache) remove(el *list.Element) {
+	entry := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= len(entry.data)
+}
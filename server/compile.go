@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// retryAfterSeconds is sent in the Retry-After header
+// when the compile queue is full
+const retryAfterSeconds = 5
+
+// compileFlights deduplicates identical in-flight compile requests
+var compileFlights = &flightGroup{}
+
+// compileQueue limits the number of concurrent compile requests
+var compileQueue *workQueue
+
+// CompileRequest is the /api/v2/compile request payload
+type CompileRequest struct {
+	Body string
+
+	// SkipImports tells to only format the code, without running goimports
+	SkipImports bool
+
+	// SkipFormat tells to leave the code as is (this implies SkipImports)
+	SkipFormat bool
+
+	// Vet tells to run go vet on the code before running it
+	Vet bool
+
+	// Test tells to run the code in test mode
+	Test bool
+
+	// Version is the Go version label (see the -versions flag);
+	// the default version is used if it is empty
+	Version string
+
+	// Stdin, Args and Env are the program standard input,
+	// command-line arguments and additional environment
+	// variables (as KEY=VALUE pairs)
+	Stdin string   `json:",omitempty"`
+	Args  []string `json:",omitempty"`
+	Env   []string `json:",omitempty"`
+
+	// Race, Tags, GOOS and GOARCH are the build options:
+	// the race detector, additional build tags, and the target
+	// platform (programs built for other platforms are not run)
+	Race   bool     `json:",omitempty"`
+	Tags   []string `json:",omitempty"`
+	GOOS   string   `json:",omitempty"`
+	GOARCH string   `json:",omitempty"`
+
+	// Profile tells to collect the CPU and heap profiles of the run
+	Profile bool `json:",omitempty"`
+
+	// Trace tells to record the execution trace of the run
+	Trace bool `json:",omitempty"`
+
+	// Fuzz is the name of the fuzz test to fuzz for a limited time
+	// instead of running the tests (Test must be set)
+	Fuzz string `json:",omitempty"`
+}
+
+var (
+	buildTagR = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	platformR = regexp.MustCompile(`^[a-z0-9]*$`)
+)
+
+// buildOptions returns the build options of the request
+func (req *CompileRequest) buildOptions() BuildOptions {
+	return BuildOptions{
+		Race:   req.Race,
+		Tags:   req.Tags,
+		GOOS:   req.GOOS,
+		GOARCH: req.GOARCH,
+	}
+}
+
+// FormatResult is the result of the formatting stage
+type FormatResult struct {
+	Error string
+}
+
+// CompileResult is the /api/v2/compile response payload.
+// Results of each stage are reported separately; stages that were
+// skipped or not reached have nil results.
+type CompileResult struct {
+	// Body is the formatted source code,
+	// if it differs from the submitted one
+	Body    *string
+	Version string
+	Format  *FormatResult
+	Vet     *VetResponse
+	Run     *CompileResponse
+}
+
+// compileV2Handler implements the /api/v2/compile API
+func compileV2Handler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	req, ok := decodeCompileRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, cached, err := processCompileRequest(r.Context(), req)
+	if err != nil {
+		writeCompileError(w, err)
+		return
+	}
+
+	bodyBytes, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("compileResult marshal error: %v", err)
+		http.Error(w, "Failed to encode data", http.StatusInternalServerError)
+		return
+	}
+
+	setCacheHeader(w, cached)
+	writeJSONBytes(w, bodyBytes)
+}
+
+// decodeCompileRequest decodes the JSON-encoded CompileRequest;
+// if it fails, it reports the error and returns false
+func decodeCompileRequest(w http.ResponseWriter, r *http.Request) (*CompileRequest, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	// JSON-encoded source code can be larger than the source itself
+	req := &CompileRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxSnippetSize)).Decode(req); err != nil {
+		http.Error(w, "Failed to decode request data", http.StatusBadRequest)
+		return nil, false
+	}
+
+	for _, kv := range req.Env {
+		if strings.IndexByte(kv, '=') <= 0 || strings.IndexByte(kv, 0) >= 0 {
+			http.Error(w, "Invalid environment variable: "+kv, http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	for _, tag := range req.Tags {
+		if !buildTagR.MatchString(tag) {
+			http.Error(w, "Invalid build tag: "+tag, http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	if !platformR.MatchString(req.GOOS) || !platformR.MatchString(req.GOARCH) {
+		http.Error(w, "Invalid GOOS or GOARCH", http.StatusBadRequest)
+		return nil, false
+	}
+	for _, arg := range req.Args {
+		if strings.IndexByte(arg, 0) >= 0 {
+			http.Error(w, "Invalid command-line argument", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	if req.Fuzz != "" && (!req.Test || !isFuzzName(req.Fuzz)) {
+		http.Error(w, "Invalid fuzz test: "+req.Fuzz, http.StatusBadRequest)
+		return nil, false
+	}
+	return req, true
+}
+
+func setCacheHeader(w http.ResponseWriter, cached bool) {
+	if cached {
+		w.Header().Set(cacheHeader, "HIT")
+	} else {
+		w.Header().Set(cacheHeader, "MISS")
+	}
+}
+
+func writeCompileError(w http.ResponseWriter, err error) {
+	if err == context.Canceled {
+		return // the client is gone
+	}
+	if err == errUnknownVersion || isNotSupported(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == errQueueFull {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// writeAnalysisResult writes the response of a code analysis API
+// (e.g. /api/v2/asm) cached under key; run is called to build
+// the response if it isn't cached, and it returns false
+// if the response must not be cached (e.g. on timeout)
+func writeAnalysisResult(w http.ResponseWriter, r *http.Request, key string, run func(ctx context.Context) (interface{}, bool, error)) {
+	bodyBytes, cached := cache.Get(key)
+	if !cached {
+		if err := compileQueue.Acquire(r.Context()); err != nil {
+			writeCompileError(w, err)
+			return
+		}
+		defer compileQueue.Release()
+
+		resp, cacheable, err := run(r.Context())
+		if err != nil {
+			if r.Context().Err() != nil {
+				return // the client is gone
+			}
+			log.Printf("%s error: %v", r.URL.Path, err)
+			writeCompileError(w, err)
+			return
+		}
+
+		bodyBytes, err = json.Marshal(resp)
+		if err != nil {
+			log.Printf("%s response marshal error: %v", r.URL.Path, err)
+			http.Error(w, "Failed to encode data", http.StatusInternalServerError)
+			return
+		}
+		if cacheable {
+			cache.Put(key, bodyBytes)
+		}
+	}
+
+	setCacheHeader(w, cached)
+	writeJSONBytes(w, bodyBytes)
+}
+
+// requestKey resolves the default version of the request,
+// and returns the cache key and the backend for the request
+func requestKey(req *CompileRequest) (string, Backend, error) {
+	// resolve the default version, so that requests with
+	// and without the explicit version share the cache entries
+	version, b, err := backendFor(req.Version)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Version = version
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return "", nil, err
+	}
+	return cacheKey(string(reqBytes)), b, nil
+}
+
+// processCompileRequest returns the result for the request from cache,
+// or runs the request (sharing the result with identical requests
+// that are in flight at the same time); the run is canceled
+// when ctx is canceled for all the requests that share it
+func processCompileRequest(ctx context.Context, req *CompileRequest) (*CompileResult, bool, error) {
+	key, b, err := requestKey(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	cached := true
+	bodyBytes, ok := cache.Get(key)
+	if !ok {
+		cached = false
+		bodyBytes, err = compileFlights.Do(ctx, key, func(ctx context.Context) ([]byte, error) {
+			if err := compileQueue.Acquire(ctx); err != nil {
+				return nil, err
+			}
+			defer compileQueue.Release()
+
+			result, err := runCompileRequest(ctx, b, req, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			return cacheResult(key, b, result)
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	result := &CompileResult{}
+	if err := json.Unmarshal(bodyBytes, result); err != nil {
+		return nil, false, err
+	}
+	return result, cached, nil
+}
+
+// runCompileRequest formats, vets, builds and runs the source code
+// with the backend; onEvent, if not nil, is called for each chunk
+// of the program output as soon as it is produced, and onFuzzProgress
+// for each progress report of the fuzzing session
+func runCompileRequest(ctx context.Context, backend Backend, req *CompileRequest, onEvent func(evt *CompileEvent), onFuzzProgress func(p *FuzzProgress)) (*CompileResult, error) {
+	result := &CompileResult{Version: req.Version}
+	body := req.Body
+	buildOptions := req.buildOptions()
+
+	if !req.SkipFormat {
+		fmtResponse, err := backend.Format(ctx, body, !req.SkipImports)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Format() error: %v", err)
+			return nil, errors.New("Failed to format source code")
+		}
+
+		result.Format = &FormatResult{Error: fmtResponse.Error}
+		if fmtResponse.Error != "" {
+			return result, nil
+		}
+
+		if fmtResponse.Body != body {
+			body = fmtResponse.Body
+			result.Body = &body
+		}
+	}
+
+	// vet runs as a part of the build, so that it doesn't take
+	// another request to the upstream playground
+	compileResponse, err := backend.Compile(ctx, body, &RunOptions{
+		BuildOptions:   buildOptions,
+		Test:           req.Test,
+		Vet:            req.Vet,
+		Stdin:          req.Stdin,
+		Args:           req.Args,
+		Env:            req.Env,
+		Profile:        req.Profile,
+		Trace:          req.Trace,
+		Fuzz:           req.Fuzz,
+		OnEvent:        onEvent,
+		OnFuzzProgress: onFuzzProgress,
+	})
+	if err != nil {
+		if isNotSupported(err) {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("Compile() error: %v", err)
+		return nil, errors.New("Failed to compile source code")
+	}
+	compileResponse.Body = nil
+	if compileResponse.VetOK || compileResponse.VetErrors != "" {
+		result.Vet = &VetResponse{Errors: compileResponse.VetErrors}
+		compileResponse.VetErrors = ""
+		compileResponse.VetOK = false
+	}
+	result.Run = compileResponse
+
+	return result, nil
+}
+
+// cacheResult returns the JSON-encoded result of the run by the backend,
+// and caches it unless it may differ between runs
+func cacheResult(key string, b Backend, result *CompileResult) ([]byte, error) {
+	bodyBytes, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("compileResult marshal error: %v", err)
+		return nil, errors.New("Failed to encode data")
+	}
+
+	// timeouts, profiles, traces and fuzzing sessions depend on server load,
+	// so they are not cached
+	if result.Run == nil || (result.Run.Errors != timeoutErrorMessage && !hasLoadDependentLimitEvent(result.Run) &&
+		result.Run.Profile == nil && result.Run.Trace == nil && result.Run.Fuzz == nil && isDeterministic(b)) {
+		cache.Put(key, bodyBytes)
+	}
+
+	return bodyBytes, nil
+}
+
+// isDeterministic returns true if the same program run by the backend
+// produces the same output: play.golang.org runs programs with fake time,
+// while the output of local runs may depend on the time, random numbers
+// or scheduling (e.g. with data races), unless -cache-local is set
+func isDeterministic(b Backend) bool {
+	_, local := b.(*localBackend)
+	return !local || cacheLocalRuns
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteAnalysisResultError(t *testing.T) {
+	cache = newCompileCache(0, 0, time.Minute, "")
+	compileQueue = newWorkQueue(1, 1)
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{errors.New("exec: \"go\": executable file not found in $PATH"), http.StatusInternalServerError},
+		{errUnknownVersion, http.StatusBadRequest},
+		{errAsmNotSupported, http.StatusBadRequest},
+		{errQueueFull, http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/v2/asm", nil)
+		w := httptest.NewRecorder()
+		writeAnalysisResult(w, r, "key", func(ctx context.Context) (interface{}, bool, error) {
+			return nil, false, test.err
+		})
+		if w.Code != test.status {
+			t.Errorf("%v: got status %d, want %d", test.err, w.Code, test.status)
+		}
+	}
+}
+
+func TestWriteAnalysisResultCanceled(t *testing.T) {
+	cache = newCompileCache(0, 0, time.Minute, "")
+	compileQueue = newWorkQueue(1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/asm", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	writeAnalysisResult(w, r, "key", func(ctx context.Context) (interface{}, bool, error) {
+		cancel()
+		return nil, false, ctx.Err()
+	})
+	if w.Body.Len() != 0 {
+		t.Errorf("got response %q for the canceled request, want none", w.Body.String())
+	}
+}
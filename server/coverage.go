@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// coverProfileFile is the coverage profile written
+// to the snippet directory by the tests
+const coverProfileFile = "goplayspace.cover"
+
+// Coverage is the test coverage of the snippet
+type Coverage struct {
+	// Percent is the percentage of the statements run by the tests
+	Percent float64
+
+	// Files are the line coverages of the snippet files by file name
+	Files map[string]*FileCoverage
+}
+
+// FileCoverage contains the line numbers of the lines that were run
+// by the tests and of those that weren't; the lines with some code
+// that wasn't run are uncovered
+type FileCoverage struct {
+	Covered   []int
+	Uncovered []int
+}
+
+var (
+	// coverBlockR matches the coverage profile lines,
+	// e.g. 'play/main.go:5.13,7.2 1 1'
+	coverBlockR = regexp.MustCompile(`^(.+\.go):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+	// majorVersionR matches the major version suffixes
+	// of import paths, e.g. 'v2' in 'math/rand/v2'
+	majorVersionR = regexp.MustCompile(`^v\d+$`)
+)
+
+// testCoverFlags returns the 'go test' flags that make it
+// build the test binary with the coverage of all snippet packages
+func testCoverFlags() []string {
+	return []string{"-cover", "-coverpkg=./..."}
+}
+
+// testCoverArgs returns the test binary arguments
+// that make it write the coverage profile
+func testCoverArgs(dir string) []string {
+	return []string{"-test.coverprofile=" + filepath.Join(dir, coverProfileFile)}
+}
+
+// isTestFunc tells if the declaration is a test, benchmark,
+// example or fuzz test function
+func isTestFunc(decl ast.Decl) bool {
+	fn, ok := decl.(*ast.FuncDecl)
+	return ok && fn.Recv == nil && testKind(fn.Name.Name) != ""
+}
+
+// importName returns the name of the imported package,
+// or an empty string if it can't be told by the import path
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	path, _ := strconv.Unquote(spec.Path.Value)
+	parts := strings.Split(path, "/")
+
+	// only the standard library package names match their paths
+	if strings.Contains(parts[0], ".") {
+		return ""
+	}
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && majorVersionR.MatchString(name) {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+// localNames adds the names declared within the declaration
+// (e.g. variables and parameters) to names
+func localNames(decl ast.Decl, names map[string]bool) {
+	add := func(ids []*ast.Ident) {
+		for _, id := range ids {
+			names[id.Name] = true
+		}
+	}
+	addFields := func(fields *ast.FieldList) {
+		if fields != nil {
+			for _, field := range fields.List {
+				add(field.Names)
+			}
+		}
+	}
+
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			addFields(n.Recv)
+		case *ast.FuncType:
+			addFields(n.TypeParams)
+			addFields(n.Params)
+			addFields(n.Results)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, x := range n.Lhs {
+					if id, ok := x.(*ast.Ident); ok {
+						names[id.Name] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, x := range []ast.Expr{n.Key, n.Value} {
+					if id, ok := x.(*ast.Ident); ok {
+						names[id.Name] = true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			add(n.Names)
+		case *ast.TypeSpec:
+			names[n.Name.Name] = true
+			addFields(n.TypeParams)
+		}
+		return true
+	})
+}
+
+// splitTestFile moves the declarations of the main test file that
+// aren't tests to progFile, since only the code outside of the test
+// files is covered. Line numbers stay the same: the moved declarations
+// are blanked in the test file and vice versa, and the imports
+// that aren't used in either file are replaced with blank ones.
+// The file is left as is if it can't be split safely (e.g. if
+// an import name is also declared in the code that refers to it).
+func splitTestFile(dir string) error {
+	path := filepath.Join(dir, testFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, data, parser.ParseComments)
+	if err != nil || strings.HasSuffix(f.Name.Name, "_test") {
+		return nil
+	}
+
+	files := map[bool][]byte{
+		false: append([]byte(nil), data...),
+		true:  append([]byte(nil), data...),
+	}
+	// the qualifiers of selector expressions are package names
+	// unless they are declared locally
+	used := map[bool]map[string]bool{
+		false: make(map[string]bool),
+		true:  make(map[string]bool),
+	}
+	declared := map[bool]map[string]bool{
+		false: make(map[string]bool),
+		true:  make(map[string]bool),
+	}
+	hasDecls := map[bool]bool{}
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		test := isTestFunc(decl)
+		hasDecls[test] = true
+
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		blank(files[!test], fset.Position(start).Offset, fset.Position(decl.End()).Offset)
+
+		localNames(decl, declared[test])
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					used[test][id.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	if !hasDecls[false] || !hasDecls[true] {
+		return nil
+	}
+
+	// the imports are edited from the end, so that the offsets stay valid
+	for i := len(f.Imports) - 1; i >= 0; i-- {
+		spec := f.Imports[i]
+		name := importName(spec)
+		if name == "" || name == "." || spec.Path.Value == `"C"` {
+			return nil
+		}
+		for _, test := range []bool{false, true} {
+			if used[test][name] && declared[test][name] {
+				return nil
+			}
+			if used[test][name] || name == "_" {
+				continue
+			}
+			if spec.Name != nil {
+				off := fset.Position(spec.Name.Pos()).Offset
+				files[test] = splice(files[test], off, off+len(spec.Name.Name), "_")
+			} else {
+				off := fset.Position(spec.Path.Pos()).Offset
+				files[test] = splice(files[test], off, off, "_ ")
+			}
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, progFile), files[false], 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, files[true], 0644)
+}
+
+// blank replaces the data between the offsets with spaces,
+// keeping the line breaks
+func blank(data []byte, start, end int) {
+	for i := start; i < end && i < len(data); i++ {
+		if data[i] != '\n' {
+			data[i] = ' '
+		}
+	}
+}
+
+// splice replaces the data between the offsets with s
+func splice(data []byte, start, end int, s string) []byte {
+	out := append([]byte(nil), data[:start]...)
+	out = append(out, s...)
+	return append(out, data[end:]...)
+}
+
+// modulePath returns the module path from the go.mod file in dir
+func modulePath(dir string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// readCoverage reads the coverage profile written by the tests;
+// it returns nil if there's no profile, or no statements to cover
+func readCoverage(dir string) *Coverage {
+	data, err := ioutil.ReadFile(filepath.Join(dir, coverProfileFile))
+	if err != nil {
+		return nil
+	}
+
+	type block struct {
+		file                string
+		startLine, startCol int
+		endLine, endCol     int
+		statements          int
+	}
+	counts := make(map[block]int)
+	var blocks []block
+
+	prefix := modulePath(dir) + "/"
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		m := coverBlockR.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		var n [6]int
+		for i := range n {
+			n[i], _ = strconv.Atoi(m[i+2])
+		}
+		b := block{strings.TrimPrefix(m[1], prefix), n[0], n[1], n[2], n[3], n[4]}
+		if _, ok := counts[b]; !ok {
+			blocks = append(blocks, b)
+		}
+		counts[b] += n[5]
+	}
+
+	total, covered := 0, 0
+	lines := make(map[string]map[int]bool)
+	sources := make(map[string][]string)
+	for _, b := range blocks {
+		if b.statements == 0 {
+			continue
+		}
+		total += b.statements
+		if counts[b] > 0 {
+			covered += b.statements
+		}
+
+		src, ok := sources[b.file]
+		if !ok {
+			data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(b.file)))
+			if err != nil {
+				log.Printf("Failed to read %s: %v", b.file, err)
+			}
+			src = strings.Split(string(data), "\n")
+			sources[b.file] = src
+		}
+
+		// the block may start after the code of its first line, and end
+		// before the code of its last one (e.g. at the braces)
+		start, end := b.startLine, b.endLine
+		if start <= len(src) && b.startCol-1 <= len(src[start-1]) {
+			if rest := strings.TrimSpace(src[start-1][b.startCol-1:]); rest == "" || rest == "{" {
+				start++
+			}
+		}
+		if end <= len(src) && b.endCol-1 <= len(src[end-1]) {
+			if rest := strings.TrimSpace(src[end-1][:b.endCol-1]); rest == "" || rest == "}" {
+				end--
+			}
+		}
+
+		if lines[b.file] == nil {
+			lines[b.file] = make(map[int]bool)
+		}
+		for line := start; line <= end; line++ {
+			if c, ok := lines[b.file][line]; !ok || c {
+				lines[b.file][line] = counts[b] > 0
+			}
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	out := &Coverage{
+		Percent: float64(covered) * 100 / float64(total),
+		Files:   make(map[string]*FileCoverage),
+	}
+	for file, fileLines := range lines {
+		fc := &FileCoverage{Covered: make([]int, 0), Uncovered: make([]int, 0)}
+		for line, c := range fileLines {
+			if c {
+				fc.Covered = append(fc.Covered, line)
+			} else {
+				fc.Uncovered = append(fc.Uncovered, line)
+			}
+		}
+		sort.Ints(fc.Covered)
+		sort.Ints(fc.Uncovered)
+		out.Files[file] = fc
+	}
+	return out
+}
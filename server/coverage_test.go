@@ -0,0 +1,372 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// trimLines removes the trailing spaces left by blanking the declarations
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+var splitTestFileTests = []struct {
+	name string
+	src  string
+
+	// wantMain and wantTest are the split files with the trailing
+	// spaces removed; the file isn't split if wantMain is empty
+	wantMain string
+	wantTest string
+}{
+	{
+		name: "imports used by one side",
+		src: `package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Hello returns the greeting
+func Hello() string {
+	return fmt.Sprint("hello")
+}
+
+// TestHello tests Hello
+func TestHello(t *testing.T) {
+	if Hello() != "hello" {
+		t.Fail()
+	}
+}
+`,
+		wantMain: `package main
+
+import (
+	"fmt"
+	_ "testing"
+)
+
+// Hello returns the greeting
+func Hello() string {
+	return fmt.Sprint("hello")
+}
+
+
+
+
+
+
+
+`,
+		wantTest: `package main
+
+import (
+	_ "fmt"
+	"testing"
+)
+
+
+
+
+
+
+// TestHello tests Hello
+func TestHello(t *testing.T) {
+	if Hello() != "hello" {
+		t.Fail()
+	}
+}
+`,
+	},
+	{
+		name: "imports used by both sides",
+		src: `package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func upper(s string) string { return strings.ToUpper(s) }
+
+func TestUpper(t *testing.T) {
+	if upper("a") != strings.ToUpper("a") {
+		t.Fail()
+	}
+}
+`,
+		wantMain: `package main
+
+import (
+	"strings"
+	_ "testing"
+)
+
+func upper(s string) string { return strings.ToUpper(s) }
+
+
+
+
+
+
+`,
+		wantTest: `package main
+
+import (
+	"strings"
+	"testing"
+)
+
+
+
+func TestUpper(t *testing.T) {
+	if upper("a") != strings.ToUpper("a") {
+		t.Fail()
+	}
+}
+`,
+	},
+	{
+		name: "renamed imports",
+		src: `package main
+
+import (
+	str "strings"
+	"testing"
+	_ "unsafe"
+)
+
+type T int
+
+func TestT(t *testing.T) {
+	_ = str.Repeat("a", int(T(1)))
+}
+`,
+		wantMain: `package main
+
+import (
+	_ "strings"
+	_ "testing"
+	_ "unsafe"
+)
+
+type T int
+
+
+
+
+`,
+		wantTest: `package main
+
+import (
+	str "strings"
+	"testing"
+	_ "unsafe"
+)
+
+
+
+func TestT(t *testing.T) {
+	_ = str.Repeat("a", int(T(1)))
+}
+`,
+	},
+	{
+		name: "shadowed import name",
+		src: `package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func host(s string) string {
+	u, _ := url.Parse(s)
+	return u.Host
+}
+
+func TestHost(t *testing.T) {
+	url := struct{ Host string }{host("http://a")}
+	if url.Host != "a" {
+		t.Fail()
+	}
+}
+`,
+	},
+	{
+		name: "dot imports",
+		src: `package main
+
+import (
+	. "strings"
+	"testing"
+)
+
+func upper(s string) string { return ToUpper(s) }
+
+func TestUpper(t *testing.T) {}
+`,
+	},
+	{
+		name: "cgo",
+		src: `package main
+
+import "C"
+import "testing"
+
+func f() {}
+
+func TestF(t *testing.T) {}
+`,
+	},
+	{
+		name: "only tests",
+		src: `package main
+
+import "testing"
+
+func TestF(t *testing.T) {}
+`,
+	},
+	{
+		name: "external test package",
+		src: `package main_test
+
+import "testing"
+
+func f() {}
+
+func TestF(t *testing.T) {}
+`,
+	},
+	{
+		name: "not a test function",
+		src: `package main
+
+import "testing"
+
+func Testify() {}
+
+func TestF(t *testing.T) { Testify() }
+`,
+		wantMain: `package main
+
+import _ "testing"
+
+func Testify() {}
+
+
+`,
+		wantTest: `package main
+
+import "testing"
+
+
+
+func TestF(t *testing.T) { Testify() }
+`,
+	},
+}
+
+func TestSplitTestFile(t *testing.T) {
+	for _, test := range splitTestFileTests {
+		dir, err := ioutil.TempDir("", "split")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		if err := ioutil.WriteFile(filepath.Join(dir, testFile), []byte(test.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := splitTestFile(dir); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		mainData, err := ioutil.ReadFile(filepath.Join(dir, progFile))
+		testData, _ := ioutil.ReadFile(filepath.Join(dir, testFile))
+		if test.wantMain == "" {
+			if err == nil {
+				t.Errorf("%s: got %s, want no split", test.name, progFile)
+			}
+			if string(testData) != test.src {
+				t.Errorf("%s: got %s:\n%s\nwant it unchanged", test.name, testFile, testData)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := trimLines(string(mainData)); got != test.wantMain {
+			t.Errorf("%s: got %s:\n%s\nwant:\n%s", test.name, progFile, got, test.wantMain)
+		}
+		if got := trimLines(string(testData)); got != test.wantTest {
+			t.Errorf("%s: got %s:\n%s\nwant:\n%s", test.name, testFile, got, test.wantTest)
+		}
+	}
+}
+
+func TestReadCoverage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coverage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"go.mod": "module play\n",
+		progFile: `package main
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+`,
+		// the blocks are listed once for each covered package,
+		// and the blocks without statements are skipped
+		coverProfileFile: `mode: set
+play/main.go:3.19,4.11 1 1
+play/main.go:4.11,6.3 1 0
+play/main.go:7.2,7.10 1 1
+play/main.go:4.11,6.3 1 0
+play/main.go:3.1,3.5 0 1
+`,
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := readCoverage(dir)
+	want := &Coverage{
+		Percent: float64(2) * 100 / 3,
+		Files: map[string]*FileCoverage{
+			"main.go": {Covered: []int{4, 7}, Uncovered: []int{5}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+		for file, fc := range got.Files {
+			t.Logf("%s: %+v", file, fc)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, coverProfileFile))
+	if got := readCoverage(dir); got != nil {
+		t.Errorf("got %+v without the profile, want nil", got)
+	}
+}
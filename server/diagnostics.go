@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Optimization diagnostic kinds
+const (
+	escapeDiagnostic = "escape"
+	movedDiagnostic  = "moved"
+	inlineDiagnostic = "inline"
+)
+
+// DiagnosticsRequest is the /api/v2/diagnostics request payload
+type DiagnosticsRequest struct {
+	Body string
+
+	// Version is the Go version label;
+	// the default version is used if it is empty
+	Version string
+}
+
+// DiagnosticsResponse is the /api/v2/diagnostics response payload
+type DiagnosticsResponse struct {
+	// Errors contains the build errors, if any
+	Errors      string
+	Diagnostics []*Diagnostic
+}
+
+// Diagnostic is a single optimization decision of the compiler
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Kind    string
+	Message string
+}
+
+// diagnoser is implemented by backends that can report
+// the optimization decisions of the compiler
+type diagnoser interface {
+	Diagnose(ctx context.Context, body string) (*DiagnosticsResponse, error)
+}
+
+// diagnosticR matches the compiler -m output, e.g.
+// './main.go:11:2: moved to heap: x'
+var diagnosticR = regexp.MustCompile(`^(.+\.go):(\d+):(\d+): (.*)$`)
+
+// diagnosticKind returns the kind of the compiler -m message,
+// or an empty string for messages that aren't reported
+func diagnosticKind(msg string) string {
+	switch {
+	case strings.HasSuffix(msg, " escapes to heap"):
+		return escapeDiagnostic
+	case strings.HasPrefix(msg, "moved to heap: "):
+		return movedDiagnostic
+	case strings.HasPrefix(msg, "can inline "):
+		return inlineDiagnostic
+	}
+	return ""
+}
+
+// diagnosticsHandler implements the /api/v2/diagnostics API
+func diagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &DiagnosticsRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxSnippetSize)).Decode(req); err != nil {
+		http.Error(w, "Failed to decode request data", http.StatusBadRequest)
+		return
+	}
+
+	version, b, err := backendFor(req.Version)
+	if err != nil {
+		writeCompileError(w, err)
+		return
+	}
+	d, ok := b.(diagnoser)
+	if !ok {
+		writeCompileError(w, errDiagnosticsNotSupported)
+		return
+	}
+	req.Version = version
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		http.Error(w, "Failed to encode data", http.StatusInternalServerError)
+		return
+	}
+	key := cacheKey("diagnostics:" + string(reqBytes))
+
+	writeAnalysisResult(w, r, key, func(ctx context.Context) (interface{}, bool, error) {
+		resp, err := d.Diagnose(ctx, req.Body)
+		if err != nil {
+			return nil, false, err
+		}
+		return resp, resp.Errors != timeoutErrorMessage, nil
+	})
+}
+
+// Diagnose implements the diagnoser interface
+func (b *localBackend) Diagnose(ctx context.Context, body string) (*DiagnosticsResponse, error) {
+	dir, err := b.prepare(body, progFile)
+	if err == errInvalidFileName {
+		return &DiagnosticsResponse{Errors: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+
+	// as with -S, the -m output is replayed for cached builds
+	out, err := b.goCommand(ctx, dir, nil, "build", "-gcflags=./...=-m", "-o", os.DevNull, "./...")
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return &DiagnosticsResponse{Errors: timeoutErrorMessage}, nil
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+		return &DiagnosticsResponse{Errors: out}, nil
+	}
+
+	return &DiagnosticsResponse{Diagnostics: parseDiagnostics(out, dir)}, nil
+}
+
+// parseDiagnostics parses the compiler -m output; file paths are made
+// relative to the snippet directory, and duplicate messages
+// (e.g. for inlined calls) are removed
+func parseDiagnostics(out, dir string) []*Diagnostic {
+	diagnostics := make([]*Diagnostic, 0)
+	seen := make(map[Diagnostic]bool)
+
+	prefix := filepath.ToSlash(dir) + "/"
+	for _, line := range strings.Split(out, "\n") {
+		m := diagnosticR.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		kind := diagnosticKind(m[4])
+		if kind == "" {
+			continue
+		}
+		file := strings.TrimPrefix(filepath.ToSlash(m[1]), prefix)
+		file = strings.TrimPrefix(file, "./")
+		n, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+
+		d := Diagnostic{File: file, Line: n, Column: col, Kind: kind, Message: m[4]}
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		diagnostics = append(diagnostics, &d)
+	}
+	return diagnostics
+}
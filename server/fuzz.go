@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// fuzzCacheDir is the fuzzing cache directory
+	// created in the snippet directory
+	fuzzCacheDir = "goplayspace.fuzzcache"
+
+	// fuzzWorkers is the number of processes running the fuzz target
+	fuzzWorkers = 2
+)
+
+// FuzzResult is the result of a fuzzing session
+type FuzzResult struct {
+	// Name is the name of the fuzz test
+	Name string
+
+	// Progress is the last reported progress; it is nil if fuzzing
+	// stopped before the first report (e.g. a seed corpus entry failed)
+	Progress *FuzzProgress `json:",omitempty"`
+
+	// Crash is the failing input found by the fuzzer, if any
+	Crash *FuzzCrash `json:",omitempty"`
+}
+
+// FuzzProgress is the fuzzing progress periodically reported
+// by the test binary
+type FuzzProgress struct {
+	// Elapsed is the fuzzing time in seconds
+	Elapsed int
+
+	// Execs is the number of the fuzz target runs so far,
+	// and ExecsPerSec is the recent rate of the runs
+	Execs       int64
+	ExecsPerSec int64
+
+	// NewInteresting is the number of inputs that expanded
+	// the code coverage during the session; TotalInteresting
+	// counts the seed corpus entries, too
+	NewInteresting   int
+	TotalInteresting int
+}
+
+// FuzzCrash is the failing input found by the fuzzer
+type FuzzCrash struct {
+	// File is the corpus file name relative to the snippet root,
+	// e.g. 'testdata/fuzz/FuzzFoo/582528ddfad69eb5'; adding this file
+	// to the snippet makes the input a part of the seed corpus
+	File string
+
+	// Input is the corpus file contents
+	Input string
+}
+
+var (
+	// fuzzNameR matches the names of the fuzz tests
+	fuzzNameR = regexp.MustCompile(`^Fuzz[\p{L}\p{N}_]*$`)
+
+	// fuzzProgressR matches the progress lines, e.g.
+	// 'fuzz: elapsed: 3s, execs: 313439 (104460/sec), new interesting: 2 (total: 3)'
+	fuzzProgressR = regexp.MustCompile(`^fuzz: elapsed: (\d+)s, execs: (\d+) \((\d+)/sec\), new interesting: (\d+) \(total: (\d+)\)`)
+
+	// fuzzCrashR matches the line reporting the failing input file, e.g.
+	// 'Failing input written to testdata/fuzz/FuzzFoo/582528ddfad69eb5'
+	fuzzCrashR = regexp.MustCompile(`Failing input written to (testdata/fuzz/[^\s/]+/[0-9a-f]+)\s*$`)
+)
+
+// isFuzzName tells if name is a valid fuzz test name
+// (the rules are the same as in 'go test')
+func isFuzzName(name string) bool {
+	return fuzzNameR.MatchString(name) && testKind(name) == "fuzz"
+}
+
+// testFuzzFlags returns the 'go test' flags that instrument
+// the snippet packages for coverage-guided fuzzing
+// (this is what 'go test -fuzz' does)
+func testFuzzFlags() []string {
+	return []string{"-gcflags=./...=-d=libfuzzer"}
+}
+
+// testFuzzArgs returns the test binary arguments that make it fuzz
+// the named fuzz test for the limited time, without running other tests
+func testFuzzArgs(dir, name string) []string {
+	return []string{
+		"-test.run=^$",
+		"-test.fuzz=^" + name + "$",
+		"-test.fuzztime=" + runLimits.FuzzTime.String(),
+		"-test.parallel=" + strconv.Itoa(fuzzWorkers),
+		"-test.fuzzcachedir=" + filepath.Join(dir, fuzzCacheDir),
+	}
+}
+
+// fuzzWriter passes the test output through to the underlying writer,
+// and tracks the progress and the failing input reported in it;
+// onProgress, if not nil, is called for each progress report
+type fuzzWriter struct {
+	out        io.Writer
+	buf        []byte
+	onProgress func(p *FuzzProgress)
+
+	progress  *FuzzProgress
+	crashFile string
+}
+
+func newFuzzWriter(out io.Writer, onProgress func(p *FuzzProgress)) *fuzzWriter {
+	return &fuzzWriter{out: out, onProgress: onProgress}
+}
+
+// Write implements the io.Writer interface
+func (w *fuzzWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i == -1 {
+			break
+		}
+		w.processLine(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return w.out.Write(p)
+}
+
+func (w *fuzzWriter) processLine(line string) {
+	if m := fuzzCrashR.FindStringSubmatch(line); m != nil {
+		w.crashFile = m[1]
+		return
+	}
+
+	m := fuzzProgressR.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	p := &FuzzProgress{}
+	p.Elapsed, _ = strconv.Atoi(m[1])
+	p.Execs, _ = strconv.ParseInt(m[2], 10, 64)
+	p.ExecsPerSec, _ = strconv.ParseInt(m[3], 10, 64)
+	p.NewInteresting, _ = strconv.Atoi(m[4])
+	p.TotalInteresting, _ = strconv.Atoi(m[5])
+	w.progress = p
+	if w.onProgress != nil {
+		w.onProgress(p)
+	}
+}
+
+// Result returns the result of the session of the named fuzz test;
+// the failing input is read from the snippet directory
+func (w *fuzzWriter) Result(dir, name string) *FuzzResult {
+	result := &FuzzResult{Name: name, Progress: w.progress}
+	if w.crashFile == "" {
+		return result
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(w.crashFile)))
+	if err != nil {
+		log.Printf("Failed to read the failing fuzz input: %v", err)
+		return result
+	}
+	result.Crash = &FuzzCrash{File: w.crashFile, Input: string(data)}
+	return result
+}
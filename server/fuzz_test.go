@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fuzzTestOutput is the captured output of a fuzzing session
+// which found a failing input
+const fuzzTestOutput = `fuzz: elapsed: 0s, gathering baseline coverage: 0/5 completed
+fuzz: elapsed: 0s, gathering baseline coverage: 5/5 completed, now fuzzing with 2 workers
+fuzz: elapsed: 3s, execs: 126585 (42194/sec), new interesting: 0 (total: 5)
+fuzz: elapsed: 6s, execs: 251170 (41528/sec), new interesting: 2 (total: 7)
+fuzz: minimizing 61-byte failing input file
+fuzz: elapsed: 7s, minimizing
+--- FAIL: FuzzReverse (7.63s)
+    --- FAIL: FuzzReverse (0.00s)
+        main_test.go:9: bad input "xy00"
+    
+    Failing input written to testdata/fuzz/FuzzReverse/a0b2f1c09a980176
+    To re-run:
+    go test -run=FuzzReverse/a0b2f1c09a980176
+FAIL
+`
+
+func TestFuzzWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const crashFile = "testdata/fuzz/FuzzReverse/a0b2f1c09a980176"
+	const crashInput = "go test fuzz v1\nstring(\"xy00\")\n"
+	path := filepath.Join(dir, filepath.FromSlash(crashFile))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(crashInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	var reports []FuzzProgress
+	w := newFuzzWriter(&out, func(p *FuzzProgress) {
+		reports = append(reports, *p)
+	})
+
+	// the output is written in chunks that split the lines
+	for data := []byte(fuzzTestOutput); len(data) > 0; {
+		n := 50
+		if n > len(data) {
+			n = len(data)
+		}
+		w.Write(data[:n])
+		data = data[n:]
+	}
+	if out.String() != fuzzTestOutput {
+		t.Errorf("got output %q, want it passed through", out.String())
+	}
+
+	wantReports := []FuzzProgress{
+		{Elapsed: 3, Execs: 126585, ExecsPerSec: 42194, NewInteresting: 0, TotalInteresting: 5},
+		{Elapsed: 6, Execs: 251170, ExecsPerSec: 41528, NewInteresting: 2, TotalInteresting: 7},
+	}
+	if !reflect.DeepEqual(reports, wantReports) {
+		t.Errorf("got progress reports %+v, want %+v", reports, wantReports)
+	}
+
+	result := w.Result(dir, "FuzzReverse")
+	want := &FuzzResult{
+		Name:     "FuzzReverse",
+		Progress: &wantReports[1],
+		Crash:    &FuzzCrash{File: crashFile, Input: crashInput},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got result %+v, want %+v", result, want)
+	}
+}
+
+func TestFuzzWriterNoProgress(t *testing.T) {
+	// a seed corpus entry fails before the fuzzing starts,
+	// and the failing input file can't be read
+	w := newFuzzWriter(ioutil.Discard, nil)
+	w.Write([]byte("fuzz: elapsed: 0s, gathering baseline coverage: 0/5 completed\n" +
+		"    Failing input written to testdata/fuzz/FuzzReverse/0123456789abcdef\n"))
+
+	result := w.Result(os.TempDir(), "FuzzReverse")
+	want := &FuzzResult{Name: "FuzzReverse"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got result %+v, want %+v", result, want)
+	}
+}
+
+func TestIsFuzzName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Fuzz", true},
+		{"FuzzReverse", true},
+		{"Fuzz_reverse", true},
+		{"Fuzzy", false},
+		{"TestReverse", false},
+		{"Fuzz.*", false},
+		{"FuzzA/b", false},
+	}
+	for _, test := range tests {
+		if got := isFuzzName(test.name); got != test.want {
+			t.Errorf("isFuzzName(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,677 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iafan/goplayspace/txtar"
+)
+
+// output chunks produced within this interval after the previous one
+// are considered simultaneous, and are merged into a single event
+// (similar to what play.golang.org does with its fake time)
+const eventMergeInterval = 10 * time.Millisecond
+
+// processes left by the program are killed as soon as it exits;
+// the output is read for at most this long after that (e.g. in case
+// some process has left the process group, and keeps the pipes open)
+const processWaitDelay = time.Second
+
+// timeoutErrorMessage is the error reported when the program
+// takes too long to build or run (same as on play.golang.org)
+const timeoutErrorMessage = "process took too long"
+
+const (
+	// progFile is the name of the main source code file for regular runs;
+	// compiler messages refer to it, and the client expects that
+	progFile = txtar.MainFile
+
+	// testFile is the name of the main source code file in test mode
+	testFile = "main_test.go"
+
+	// binFile is the name of the compiled program
+	binFile = "prog"
+)
+
+// defaultGoModVersion is the go.mod language version used
+// if the toolchain version can't be determined
+const defaultGoModVersion = "1.16"
+
+var goVersionR = regexp.MustCompile(`^go(\d+\.\d+)`)
+
+var errInvalidFileName = errors.New("Invalid file name in the snippet")
+
+// localBackend builds and runs code using the local Go toolchain
+type localBackend struct {
+	GoBin   string
+	Timeout time.Duration
+
+	// GOROOT is the toolchain root directory; if it is set,
+	// the toolchain is used as is (without automatic toolchain switching)
+	GOROOT string
+
+	versionOnce sync.Once
+	version     string
+}
+
+// Format implements the Backend interface.
+// To fix imports, it uses the goimports binary if it is available in PATH,
+// and falls back to plain gofmt otherwise. For multi-file snippets,
+// each .go file is formatted separately.
+func (b *localBackend) Format(ctx context.Context, body string, imports bool) (*FmtResponse, error) {
+	if !txtar.IsArchive([]byte(body)) {
+		out, msg, err := formatFile(ctx, body, imports)
+		return &FmtResponse{Body: out, Error: msg}, err
+	}
+
+	files := txtar.Split([]byte(body))
+	for i, f := range files {
+		if filepath.Ext(f.Name) != ".go" {
+			continue
+		}
+		out, msg, err := formatFile(ctx, string(f.Data), imports)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			return &FmtResponse{Error: prefixLines(msg, f.Name+":")}, nil
+		}
+		files[i].Data = []byte(out)
+	}
+	return &FmtResponse{Body: string(txtar.Join(files))}, nil
+}
+
+// formatFile formats a single source code file; the formatting error
+// is returned as a message in the '3:1: error text' format
+// to match the play.golang.org one
+func formatFile(ctx context.Context, body string, imports bool) (string, string, error) {
+	goimports, err := exec.LookPath("goimports")
+	if !imports || err != nil {
+		out, err := format.Source([]byte(body))
+		if err != nil {
+			return "", err.Error(), nil
+		}
+		return string(out), "", nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goimports)
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", "", ctx.Err()
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return "", "", err
+		}
+		return "", strings.Replace(stderr.String(), "<standard input>:", "", -1), nil
+	}
+
+	return stdout.String(), "", nil
+}
+
+// prefixLines adds the prefix to each non-empty line of s
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// vet runs go vet on the snippet in dir
+func (b *localBackend) vet(ctx context.Context, dir string, opts *BuildOptions) (*VetResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+
+	vetArgs := []string{"vet"}
+	if len(opts.Tags) > 0 {
+		vetArgs = append(vetArgs, "-tags", strings.Join(opts.Tags, ","))
+	}
+	out, err := b.goCommand(ctx, dir, opts.env(), append(vetArgs, ".")...)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return &VetResponse{Errors: timeoutErrorMessage}, nil
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+	}
+	return &VetResponse{Errors: out}, nil
+}
+
+// Compile implements the Backend interface
+func (b *localBackend) Compile(ctx context.Context, body string, opts *RunOptions) (*CompileResponse, error) {
+	// the trace is read with 'go tool trace -d=parsed' (see readTrace),
+	// which appeared in Go 1.22
+	if opts.Trace && !b.goVersionAtLeast(1, 22) {
+		return nil, errTraceNotSupported
+	}
+
+	mainFile := progFile
+	if opts.Test {
+		mainFile = testFile
+	}
+
+	dir, err := b.prepare(body, mainFile)
+	if err == errInvalidFileName {
+		return &CompileResponse{Errors: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// only the snippet files are vetted, not the generated ones
+	var vetResponse *VetResponse
+	if opts.Vet {
+		if vetResponse, err = b.vet(ctx, dir, &opts.BuildOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	// the profile is written before the trace is stopped,
+	// so that stopping the trace isn't profiled
+	if opts.Profile && !opts.Test {
+		if err := addProfileMain(dir); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Trace && !opts.Test {
+		if err := addTraceMain(dir); err != nil {
+			return nil, err
+		}
+	}
+	// the coverage of fuzzing sessions isn't reported
+	coverage := opts.Test && opts.Fuzz == ""
+	if coverage {
+		if err := splitTestFile(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+
+	buildArgs := []string{"build"}
+	if coverage {
+		buildArgs = append([]string{"test", "-c"}, testCoverFlags()...)
+	} else if opts.Test {
+		buildArgs = append([]string{"test", "-c"}, testFuzzFlags()...)
+	}
+	buildArgs = append(buildArgs, opts.flags()...)
+	buildArgs = append(buildArgs, "-o", binFile, ".")
+
+	buildOutput, err := b.goCommand(ctx, dir, opts.env(), buildArgs...)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return &CompileResponse{Errors: timeoutErrorMessage}, nil
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+		return &CompileResponse{Errors: buildOutput}, nil
+	}
+
+	// programs built for other platforms can't be run
+	if opts.isCrossCompile() {
+		compileResponse := &CompileResponse{
+			Events:    make([]*CompileEvent, 0),
+			IsTest:    opts.Test,
+			BuildOnly: true,
+		}
+		compileResponse.setVet(vetResponse)
+		return compileResponse, nil
+	}
+
+	compileResponse, err := b.run(ctx, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Test {
+		compileResponse.IsTest = true
+		compileResponse.TestsFailed = countFailedTests(compileResponse.Events)
+	}
+	compileResponse.setVet(vetResponse)
+	return compileResponse, nil
+}
+
+// streamsOutput implements the streamer interface
+func (b *localBackend) streamsOutput() {}
+
+// prepare creates a temporary module directory with the snippet files
+// (the main file is saved as mainFile); the caller is responsible
+// for removing the directory. Files with the same name (e.g. the archive
+// comment and an explicit main.go) are rejected with errInvalidFileName.
+func (b *localBackend) prepare(body, mainFile string) (string, error) {
+	files := txtar.Split([]byte(body))
+	names := make(map[string]bool)
+	for i, f := range files {
+		name := filepath.Clean(filepath.FromSlash(f.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return "", errInvalidFileName
+		}
+		if name == txtar.MainFile {
+			name = mainFile
+		}
+		if names[name] {
+			return "", errInvalidFileName
+		}
+		names[name] = true
+		files[i].Name = name
+	}
+
+	if !names["go.mod"] {
+		files = append(files, txtar.File{
+			Name: "go.mod",
+			Data: []byte("module play\n\ngo " + b.goVersion() + "\n"),
+		})
+	}
+
+	dir, err := ioutil.TempDir("", "goplayspace")
+	if err != nil {
+		return "", err
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(path, f.Data, 0644)
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// goVersion returns the language version of the local toolchain
+// (e.g. '1.21') for the go.mod files of snippets that don't have one
+func (b *localBackend) goVersion() string {
+	b.versionOnce.Do(func() {
+		b.version = defaultGoModVersion
+		out, err := b.command(context.Background(), "", "env", "GOVERSION").Output()
+		if err != nil {
+			log.Printf("Failed to get the Go version: %v", err)
+			return
+		}
+		if m := goVersionR.FindStringSubmatch(string(out)); m != nil {
+			b.version = m[1]
+		}
+	})
+	return b.version
+}
+
+// goVersionAtLeast tells if the language version
+// of the local toolchain is at least major.minor
+func (b *localBackend) goVersionAtLeast(major, minor int) bool {
+	var vmajor, vminor int
+	fmt.Sscanf(b.goVersion(), "%d.%d", &vmajor, &vminor)
+	return vmajor > major || (vmajor == major && vminor >= minor)
+}
+
+// testVerboseFlag returns the flag for verbose test output; Go 1.20+
+// test binaries can frame the output of each test for test2json,
+// so that e.g. benchmark output isn't attributed to the previous test
+func (b *localBackend) testVerboseFlag() string {
+	if b.goVersionAtLeast(1, 20) {
+		return "-test.v=test2json"
+	}
+	return "-test.v"
+}
+
+// command returns the go tool command to be run in dir
+func (b *localBackend) command(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, b.GoBin, args...)
+	cmd.Dir = dir
+	if b.GOROOT != "" {
+		cmd.Env = append(os.Environ(), "GOROOT="+b.GOROOT, "GOTOOLCHAIN=local")
+	}
+	return cmd
+}
+
+// goCommand runs the go tool with the additional environment
+// variables, and returns its combined output
+func (b *localBackend) goCommand(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := b.command(ctx, dir, args...)
+	if len(env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
+	out, err := cmd.CombinedOutput()
+
+	// remove the '# command-line-arguments' headers,
+	// and make test file names look like regular ones
+	lines := strings.Split(string(out), "\n")
+	filtered := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "# ") {
+			filtered = append(filtered, strings.Replace(line, testFile, progFile, -1))
+		}
+	}
+	return strings.Join(filtered, "\n"), err
+}
+
+// run runs the compiled program; test binaries are run
+// via 'go tool test2json' (this is what 'go test -json' does)
+// to get the structured test results
+func (b *localBackend) run(ctx context.Context, dir string, opts *RunOptions) (*CompileResponse, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	if runLimits.WallTime > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, runLimits.WallTime)
+	}
+	defer cancel()
+
+	// the program is stopped as soon as it exceeds the output limit
+	rec := newEventRecorder(opts.OnEvent)
+	rec.SetLimit(runLimits.Output, cancel)
+
+	cmd := exec.CommandContext(runCtx, filepath.Join(dir, binFile), opts.Args...)
+	cmd.Dir = dir
+	cmd.Stdout = rec.Writer("stdout")
+	cmd.Stderr = rec.Writer("stderr")
+
+	var tests *testJSONWriter
+	var fuzz *fuzzWriter
+	if opts.Test {
+		// test2json merges stdout and stderr of the test binary
+		// (it doesn't pass its stdin to the test binary, though)
+		tests = newTestJSONWriter(cmd.Stdout)
+		args := []string{"tool", "test2json", "-t", "./" + binFile, b.testVerboseFlag()}
+		if opts.Fuzz != "" {
+			fuzz = newFuzzWriter(cmd.Stdout, opts.OnFuzzProgress)
+			tests = newTestJSONWriter(fuzz)
+			args = append(args, testFuzzArgs(dir, opts.Fuzz)...)
+		} else {
+			args = append(args, "-test.bench=.")
+			args = append(args, testCoverArgs(dir)...)
+		}
+		if opts.Profile {
+			args = append(args, testProfileArgs(dir)...)
+		}
+		if opts.Trace {
+			args = append(args, testTraceArgs(dir)...)
+		}
+		args = append(args, opts.Args...)
+		cmd = b.command(runCtx, dir, args...)
+		cmd.Stdout = tests
+		cmd.Stderr = rec.Writer("stderr")
+	}
+
+	// the race detector reserves a lot of memory for its shadow memory,
+	// so the memory limit doesn't work with it
+	limits := *runLimits
+	if opts.Race {
+		limits.Memory = 0
+	}
+
+	cmd.Stdin = strings.NewReader(opts.Stdin)
+	limits.command(cmd, dir)
+	cmd.Env = append(cmd.Env, opts.Env...)
+
+	// the output pipes are created here, so that waiting for the program
+	// doesn't wait for the processes it has left running (os/exec waits
+	// until all the processes close the pipes it creates, and it stops
+	// watching the context once the program exits)
+	stdout, err := newOutputPipe(cmd.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := newOutputPipe(cmd.Stderr)
+	if err != nil {
+		stdout.Wait(0)
+		return nil, err
+	}
+	cmd.Stdout = stdout.File
+	cmd.Stderr = stderr.File
+	cmd.WaitDelay = processWaitDelay
+
+	rec.Start()
+	err = cmd.Start()
+	if err == nil {
+		err = cmd.Wait()
+	}
+
+	// processes left by the program are killed, too
+	killProcessGroup(cmd)
+	stdout.Wait(processWaitDelay)
+	stderr.Wait(processWaitDelay)
+
+	// the stdin pipe may be kept open by such processes
+	if err == exec.ErrWaitDelay {
+		err = nil
+	}
+
+	if ctx.Err() == context.Canceled {
+		return nil, ctx.Err()
+	}
+	if ctx.Err() != nil {
+		resp := &CompileResponse{Events: rec.Events(), Errors: timeoutErrorMessage}
+		if tests != nil {
+			resp.Tests = tests.Results(-1)
+		}
+		if fuzz != nil {
+			resp.Fuzz = fuzz.Result(dir, opts.Fuzz)
+		}
+		return resp, nil
+	}
+
+	status := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, err
+		}
+		status = exitErr.ExitCode()
+	}
+
+	events := rec.Events()
+	if rec.LimitExceeded() {
+		events = append(events, limits.outputLimitEvent())
+	} else if evt := limits.limitEvent(cmd.ProcessState, events, runCtx.Err() != nil); evt != nil {
+		events = append(events, evt)
+	}
+
+	resp := &CompileResponse{Events: events, Status: status}
+	if tests != nil {
+		resp.Tests = tests.Results(status)
+		resp.Coverage = readCoverage(dir)
+	}
+	if fuzz != nil {
+		resp.Fuzz = fuzz.Result(dir, opts.Fuzz)
+	}
+	if opts.Profile {
+		resp.Profile = readProfile(dir)
+	}
+	if opts.Trace {
+		resp.Trace = b.readTrace(ctx, dir)
+	}
+	return resp, nil
+}
+
+// outputPipe copies the output written to File to the underlying writer
+type outputPipe struct {
+	File *os.File
+
+	r    *os.File
+	done chan struct{}
+}
+
+func newOutputPipe(w io.Writer) (*outputPipe, error) {
+	r, f, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	p := &outputPipe{File: f, r: r, done: make(chan struct{})}
+	go func() {
+		io.Copy(w, r)
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Wait closes File, and waits until all the output is copied;
+// if other processes still have the pipe open after timeout,
+// the rest of the output is dropped
+func (p *outputPipe) Wait(timeout time.Duration) {
+	p.File.Close()
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+	}
+	p.r.Close()
+	<-p.done
+}
+
+// countFailedTests returns the number of '--- FAIL' lines
+// in the test output (this is what play.golang.org does, too)
+func countFailedTests(events []*CompileEvent) int {
+	n := 0
+	for _, evt := range events {
+		if evt.Kind == "stdout" {
+			n += strings.Count(evt.Message, "--- FAIL")
+		}
+	}
+	return n
+}
+
+// eventRecorder collects the program output as a list of CompileEvent records;
+// if onEvent is not nil, it is also called for each output chunk
+type eventRecorder struct {
+	mu      sync.Mutex
+	last    time.Time
+	events  []*CompileEvent
+	onEvent func(evt *CompileEvent)
+
+	size       int64
+	limit      int64
+	onExceeded func()
+	exceeded   bool
+}
+
+func newEventRecorder(onEvent func(evt *CompileEvent)) *eventRecorder {
+	return &eventRecorder{events: make([]*CompileEvent, 0), onEvent: onEvent}
+}
+
+// Start resets the time origin for event delays
+func (r *eventRecorder) Start() {
+	r.mu.Lock()
+	r.last = time.Now()
+	r.mu.Unlock()
+}
+
+// SetLimit limits the total size of the recorded output;
+// the rest of the output is dropped, and onExceeded is called
+// once the limit is exceeded (0 means no limit)
+func (r *eventRecorder) SetLimit(limit int64, onExceeded func()) {
+	r.mu.Lock()
+	r.limit = limit
+	r.onExceeded = onExceeded
+	r.mu.Unlock()
+}
+
+// LimitExceeded returns true if some of the output was dropped
+func (r *eventRecorder) LimitExceeded() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exceeded
+}
+
+// Writer returns an io.Writer which records events of the given kind
+func (r *eventRecorder) Writer(kind string) *eventWriter {
+	return &eventWriter{r, kind}
+}
+
+// Events returns the list of recorded events
+func (r *eventRecorder) Events() []*CompileEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events
+}
+
+func (r *eventRecorder) add(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.limit > 0 {
+		if r.exceeded {
+			return
+		}
+		if r.size+int64(len(p)) > r.limit {
+			p = p[:r.limit-r.size]
+			r.exceeded = true
+			r.onExceeded()
+		}
+		r.size += int64(len(p))
+		if len(p) == 0 {
+			return
+		}
+	}
+
+	now := time.Now()
+	delay := now.Sub(r.last)
+	if delay < eventMergeInterval {
+		delay = 0
+	} else {
+		r.last = now
+	}
+
+	if r.onEvent != nil {
+		r.onEvent(&CompileEvent{
+			Message: string(p),
+			Kind:    kind,
+			Delay:   delay,
+		})
+	}
+
+	if n := len(r.events); n > 0 && delay == 0 && r.events[n-1].Kind == kind {
+		r.events[n-1].Message += string(p)
+		return
+	}
+
+	r.events = append(r.events, &CompileEvent{
+		Message: string(p),
+		Kind:    kind,
+		Delay:   delay,
+	})
+}
+
+type eventWriter struct {
+	rec  *eventRecorder
+	kind string
+}
+
+// Write implements the io.Writer interface
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.rec.add(w.kind, p)
+	return len(p), nil
+}
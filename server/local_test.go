@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBackend returns the local backend using the go tool from PATH
+func newTestBackend(t *testing.T) *localBackend {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool is not available")
+	}
+	return &localBackend{GoBin: goBin, Timeout: time.Minute}
+}
+
+func TestRunBackgroundProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process groups are only killed on Linux")
+	}
+	b := newTestBackend(t)
+	runLimits = &sandboxLimits{WallTime: 30 * time.Second}
+
+	// the background process inherits the program stdout
+	const prog = `package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func main() {
+	cmd := exec.Command("sh", "-c", "sleep 100; echo late")
+	cmd.Stdout = os.Stdout
+	if err := cmd.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println("done")
+}
+`
+	start := time.Now()
+	resp, err := b.Compile(context.Background(), prog, &RunOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Errors != "" {
+		t.Fatalf("got errors %q", resp.Errors)
+	}
+
+	// the run takes the build time plus processWaitDelay at most
+	if d := time.Since(start); d > 20*time.Second {
+		t.Errorf("the run took %v, the background process wasn't killed", d)
+	}
+	var out strings.Builder
+	for _, evt := range resp.Events {
+		out.WriteString(evt.Message)
+	}
+	if out.String() != "done\n" {
+		t.Errorf("got output %q, want %q", out.String(), "done\n")
+	}
+}
+
+func TestGoVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version      string
+		major, minor int
+		want         bool
+	}{
+		{"1.22", 1, 22, true},
+		{"1.22", 1, 20, true},
+		{"1.21", 1, 22, false},
+		{"1.9", 1, 20, false},
+		{"2.0", 1, 22, true},
+	}
+	for _, test := range tests {
+		b := &localBackend{}
+		b.versionOnce.Do(func() { b.version = test.version })
+		if got := b.goVersionAtLeast(test.major, test.minor); got != test.want {
+			t.Errorf("%s at least %d.%d: got %v, want %v", test.version, test.major, test.minor, got, test.want)
+		}
+	}
+}
+
+func TestTraceNotSupported(t *testing.T) {
+	b := &localBackend{}
+	b.versionOnce.Do(func() { b.version = "1.21" })
+	if _, err := b.Compile(context.Background(), "package main\n\nfunc main() {}\n", &RunOptions{Trace: true}); err != errTraceNotSupported {
+		t.Errorf("got error %v, want %v", err, errTraceNotSupported)
+	}
+}
+
+func TestPrepareDuplicateFiles(t *testing.T) {
+	b := &localBackend{}
+	b.versionOnce.Do(func() { b.version = "1.22" })
+
+	tests := []struct {
+		body     string
+		mainFile string
+		wantErr  error
+	}{
+		{"package main\n-- main.go --\npackage main\n", progFile, errInvalidFileName},
+		{"package main\n-- ./main.go --\npackage main\n", progFile, errInvalidFileName},
+		{"package main\n-- main_test.go --\npackage main\n", testFile, errInvalidFileName},
+		{"-- go.mod --\nmodule a\n-- go.mod --\nmodule b\n", progFile, errInvalidFileName},
+		{"-- ../main.go --\npackage main\n", progFile, errInvalidFileName},
+		{"package main\n-- main.go --\npackage main\n", testFile, errInvalidFileName},
+		{"package main\n-- util.go --\npackage main\n", testFile, nil},
+		{"\n-- main.go --\npackage main\n", progFile, nil},
+	}
+	for _, test := range tests {
+		dir, err := b.prepare(test.body, test.mainFile)
+		if err == nil {
+			os.RemoveAll(dir)
+		}
+		if err != test.wantErr {
+			t.Errorf("%q as %s: got error %v, want %v", test.body, test.mainFile, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+)
+
+// This file decodes the subset of the pprof profile format
+// (https://github.com/google/pprof/blob/main/proto/profile.proto)
+// needed to show stack samples: the server only uses the standard library.
+
+var errInvalidProfile = errors.New("invalid profile data")
+
+// Protocol buffers wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoField is a single field of a protocol buffers message;
+// data is only set for the length-delimited fields
+type protoField struct {
+	num  int
+	wire int
+	val  uint64
+	data []byte
+}
+
+// protoFields splits the message into fields
+func protoFields(b []byte) ([]protoField, error) {
+	var out []protoField
+	for len(b) > 0 {
+		key, n := protoVarint(b)
+		if n == 0 {
+			return nil, errInvalidProfile
+		}
+		b = b[n:]
+
+		f := protoField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.val, n = protoVarint(b)
+			if n == 0 {
+				return nil, errInvalidProfile
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errInvalidProfile
+			}
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errInvalidProfile
+			}
+			b = b[4:]
+		case wireBytes:
+			size, n := protoVarint(b)
+			if n == 0 || uint64(len(b)-n) < size {
+				return nil, errInvalidProfile
+			}
+			f.data = b[n : n+int(size)]
+			b = b[n+int(size):]
+		default:
+			return nil, errInvalidProfile
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// protoVarint decodes a varint; n is 0 if the data is invalid
+func protoVarint(b []byte) (v uint64, n int) {
+	for shift := uint(0); shift < 64 && n < len(b); shift += 7 {
+		c := b[n]
+		n++
+		v |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return v, n
+		}
+	}
+	return 0, 0
+}
+
+// uints returns the values of a repeated integer field,
+// which may be packed or not
+func (f *protoField) uints() ([]uint64, error) {
+	if f.wire == wireVarint {
+		return []uint64{f.val}, nil
+	}
+	if f.wire != wireBytes {
+		return nil, errInvalidProfile
+	}
+	var out []uint64
+	for b := f.data; len(b) > 0; {
+		v, n := protoVarint(b)
+		if n == 0 {
+			return nil, errInvalidProfile
+		}
+		out = append(out, v)
+		b = b[n:]
+	}
+	return out, nil
+}
+
+// pprofProfile is the decoded profile
+type pprofProfile struct {
+	sampleTypes []string
+	samples     []*pprofSample
+	locations   map[uint64][]pprofLine
+	functions   map[uint64]*pprofFunction
+	strings     []string
+}
+
+type pprofSample struct {
+	locations []uint64
+	values    []int64
+}
+
+// pprofLine is a source line of a location; locations
+// of inlined calls have several lines, the innermost one first
+type pprofLine struct {
+	function uint64
+	line     int64
+}
+
+type pprofFunction struct {
+	name     int64
+	filename int64
+}
+
+// decodePprof decodes the profile, which may be gzipped
+func decodePprof(data []byte) (*pprofProfile, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pprofProfile{
+		locations: make(map[uint64][]pprofLine),
+		functions: make(map[uint64]*pprofFunction),
+	}
+	var sampleTypes []uint64
+	for _, f := range fields {
+		switch f.num {
+		case 1: // sample_type
+			vt, err := protoFields(f.data)
+			if err != nil {
+				return nil, err
+			}
+			var typ uint64
+			for _, v := range vt {
+				if v.num == 1 {
+					typ = v.val
+				}
+			}
+			sampleTypes = append(sampleTypes, typ)
+		case 2: // sample
+			s, err := decodePprofSample(f.data)
+			if err != nil {
+				return nil, err
+			}
+			p.samples = append(p.samples, s)
+		case 4: // location
+			if err := p.decodeLocation(f.data); err != nil {
+				return nil, err
+			}
+		case 5: // function
+			if err := p.decodeFunction(f.data); err != nil {
+				return nil, err
+			}
+		case 6: // string_table
+			p.strings = append(p.strings, string(f.data))
+		}
+	}
+
+	for _, typ := range sampleTypes {
+		p.sampleTypes = append(p.sampleTypes, p.str(int64(typ)))
+	}
+	return p, nil
+}
+
+func decodePprofSample(data []byte) (*pprofSample, error) {
+	fields, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+	s := &pprofSample{}
+	for _, f := range fields {
+		switch f.num {
+		case 1: // location_id
+			ids, err := f.uints()
+			if err != nil {
+				return nil, err
+			}
+			s.locations = append(s.locations, ids...)
+		case 2: // value
+			values, err := f.uints()
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range values {
+				s.values = append(s.values, int64(v))
+			}
+		}
+	}
+	return s, nil
+}
+
+func (p *pprofProfile) decodeLocation(data []byte) error {
+	fields, err := protoFields(data)
+	if err != nil {
+		return err
+	}
+	var id uint64
+	var lines []pprofLine
+	for _, f := range fields {
+		switch f.num {
+		case 1: // id
+			id = f.val
+		case 4: // line
+			lf, err := protoFields(f.data)
+			if err != nil {
+				return err
+			}
+			var line pprofLine
+			for _, v := range lf {
+				switch v.num {
+				case 1:
+					line.function = v.val
+				case 2:
+					line.line = int64(v.val)
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	p.locations[id] = lines
+	return nil
+}
+
+func (p *pprofProfile) decodeFunction(data []byte) error {
+	fields, err := protoFields(data)
+	if err != nil {
+		return err
+	}
+	var id uint64
+	fn := &pprofFunction{}
+	for _, f := range fields {
+		switch f.num {
+		case 1: // id
+			id = f.val
+		case 2: // name
+			fn.name = int64(f.val)
+		case 4: // filename
+			fn.filename = int64(f.val)
+		}
+	}
+	p.functions[id] = fn
+	return nil
+}
+
+func (p *pprofProfile) str(i int64) string {
+	if i < 0 || i >= int64(len(p.strings)) {
+		return ""
+	}
+	return p.strings[i]
+}
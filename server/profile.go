@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// profileMainFile is the generated file with the main function
+	// that runs the original one (renamed to profiledMainFunc)
+	// with profiling
+	profileMainFile  = "goplayspace_profile.go"
+	profiledMainFunc = "goplayspaceProfiledMain"
+
+	// cpuProfileFile and heapProfileFile are the profiles
+	// written to the snippet directory
+	cpuProfileFile  = "goplayspace-cpu.pprof"
+	heapProfileFile = "goplayspace-heap.pprof"
+)
+
+// profileMainTemplate is the generated main function; the imports
+// are renamed, so that they don't clash with the snippet declarations
+const profileMainTemplate = `package main
+
+import (
+	gpsos "os"
+	gpsruntime "runtime"
+	gpspprof "runtime/pprof"
+)
+
+func main() {
+	cpu, err := gpsos.Create(%q)
+	if err == nil {
+		gpspprof.StartCPUProfile(cpu)
+	}
+	defer func() {
+		if err == nil {
+			gpspprof.StopCPUProfile()
+			cpu.Close()
+		}
+		if heap, err := gpsos.Create(%q); err == nil {
+			gpsruntime.GC()
+			gpspprof.WriteHeapProfile(heap)
+			heap.Close()
+		}
+	}()
+	%s()
+}
+`
+
+// Profile contains the profiles of the program run
+type Profile struct {
+	CPU  *ProfileData `json:",omitempty"`
+	Heap *ProfileData `json:",omitempty"`
+}
+
+// ProfileData is a profile of a single kind
+type ProfileData struct {
+	// Unit is the unit of the values, 'nanoseconds' or 'bytes'
+	Unit  string
+	Total int64
+
+	// Frames are the stack frames referred to by the samples
+	Frames  []*ProfileFrame
+	Samples []*ProfileSample
+}
+
+// ProfileFrame is a function call; File is relative to the snippet
+// directory for snippet files (e.g. 'main.go')
+type ProfileFrame struct {
+	Func string
+	File string
+	Line int
+}
+
+// ProfileSample is the total value of the samples with the same stack
+type ProfileSample struct {
+	// Stack contains the Frames indices, the root (e.g. 'main.main') first
+	Stack []int
+	Value int64
+}
+
+// addProfileMain wraps the main function of the snippet in dir
+// with the one that runs it with profiling
+func addProfileMain(dir string) error {
+	src := fmt.Sprintf(profileMainTemplate,
+		filepath.Join(dir, cpuProfileFile),
+		filepath.Join(dir, heapProfileFile),
+		profiledMainFunc)
+	return wrapMain(dir, profileMainFile, profiledMainFunc, src)
+}
+
+// testProfileArgs returns the test binary arguments
+// that make it write the profiles
+func testProfileArgs(dir string) []string {
+	return []string{
+		"-test.cpuprofile=" + filepath.Join(dir, cpuProfileFile),
+		"-test.memprofile=" + filepath.Join(dir, heapProfileFile),
+	}
+}
+
+// readProfile reads the profiles written by the program;
+// the ones that are missing (e.g. if the program called os.Exit)
+// or invalid are omitted
+func readProfile(dir string) *Profile {
+	out := &Profile{}
+	out.CPU = readProfileData(filepath.Join(dir, cpuProfileFile), "cpu", "nanoseconds", dir)
+	out.Heap = readProfileData(filepath.Join(dir, heapProfileFile), "alloc_space", "bytes", dir)
+	return out
+}
+
+func readProfileData(path, sampleType, unit, dir string) *ProfileData {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	p, err := decodePprof(data)
+	if err != nil {
+		log.Printf("Failed to decode %s: %v", filepath.Base(path), err)
+		return nil
+	}
+
+	idx := -1
+	for i, typ := range p.sampleTypes {
+		if typ == sampleType {
+			idx = i
+		}
+	}
+	if idx == -1 {
+		return nil
+	}
+
+	out := &ProfileData{
+		Unit:    unit,
+		Frames:  make([]*ProfileFrame, 0),
+		Samples: make([]*ProfileSample, 0),
+	}
+	frames := make(map[pprofLine]int)
+	samples := make(map[string]*ProfileSample)
+
+	frameIndex := func(line pprofLine) int {
+		if i, ok := frames[line]; ok {
+			return i
+		}
+		frame := &ProfileFrame{Line: int(line.line)}
+		if fn := p.functions[line.function]; fn != nil {
+			frame.Func = snippetFuncName(p.str(fn.name))
+			frame.File = snippetFileName(p.str(fn.filename), dir)
+		}
+		// the generated main functions are omitted
+		if isGeneratedFile(frame.File) {
+			frames[line] = -1
+			return -1
+		}
+		frames[line] = len(out.Frames)
+		out.Frames = append(out.Frames, frame)
+		return frames[line]
+	}
+
+	for _, s := range p.samples {
+		if idx >= len(s.values) || s.values[idx] == 0 {
+			continue
+		}
+
+		// the locations are listed leaf first, and so are
+		// the lines of inlined calls within a location
+		var stack []int
+		profiler := false
+		for i := len(s.locations) - 1; i >= 0; i-- {
+			lines := p.locations[s.locations[i]]
+			for j := len(lines) - 1; j >= 0; j-- {
+				if frame := frameIndex(lines[j]); frame != -1 {
+					stack = append(stack, frame)
+					profiler = profiler || strings.HasPrefix(out.Frames[frame].Func, "runtime/pprof.")
+				}
+			}
+		}
+		// allocations made by the profiler itself are omitted
+		if profiler {
+			continue
+		}
+
+		keys := make([]string, len(stack))
+		for i, frame := range stack {
+			keys[i] = strconv.Itoa(frame)
+		}
+		key := strings.Join(keys, ",")
+
+		if samples[key] == nil {
+			samples[key] = &ProfileSample{Stack: stack}
+			out.Samples = append(out.Samples, samples[key])
+		}
+		samples[key].Value += s.values[idx]
+		out.Total += s.values[idx]
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+var profileTestSink []byte
+
+func profileTestAlloc() {
+	profileTestSink = make([]byte, 1<<20)
+}
+
+// writeTestHeapProfile writes the heap profile with the allocations
+// made by profileTestAlloc to dir
+func writeTestHeapProfile(t *testing.T, dir string) string {
+	rate := runtime.MemProfileRate
+	runtime.MemProfileRate = 1
+	defer func() { runtime.MemProfileRate = rate }()
+
+	profileTestAlloc()
+	runtime.GC()
+
+	path := filepath.Join(dir, heapProfileFile)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadProfileData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeTestHeapProfile(t, dir)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := decodePprof(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"alloc_objects", "alloc_space", "inuse_objects", "inuse_space"}
+	if strings.Join(p.sampleTypes, ",") != strings.Join(want, ",") {
+		t.Errorf("got sample types %q, want %q", p.sampleTypes, want)
+	}
+
+	prof := readProfileData(path, "alloc_space", "bytes", dir)
+	if prof == nil {
+		t.Fatal("got no profile")
+	}
+	if prof.Unit != "bytes" {
+		t.Errorf("got unit %q, want bytes", prof.Unit)
+	}
+
+	var total int64
+	found := false
+	for _, s := range prof.Samples {
+		total += s.Value
+		for _, i := range s.Stack {
+			if i < 0 || i >= len(prof.Frames) {
+				t.Fatalf("got frame index %d, want [0, %d)", i, len(prof.Frames))
+			}
+		}
+
+		// the stack is listed root first
+		test, alloc := -1, -1
+		for j, i := range s.Stack {
+			// the package path is 'main' in the programs,
+			// and the import path in the tests
+			switch name := prof.Frames[i].Func; {
+			case strings.HasSuffix(name, ".TestReadProfileData"):
+				test = j
+			case strings.HasSuffix(name, ".profileTestAlloc"):
+				alloc = j
+			}
+		}
+		if alloc == -1 {
+			continue
+		}
+		found = true
+		if test == -1 || test > alloc {
+			t.Errorf("got stack %v, want the test function before profileTestAlloc", s.Stack)
+		}
+		frame := prof.Frames[s.Stack[alloc]]
+		if filepath.Base(frame.File) != "profile_test.go" || frame.Line == 0 {
+			t.Errorf("got profileTestAlloc at %s:%d, want profile_test.go", frame.File, frame.Line)
+		}
+		if s.Value < 1<<20 {
+			t.Errorf("got profileTestAlloc value %d, want at least %d", s.Value, 1<<20)
+		}
+	}
+	if !found {
+		t.Error("got no samples of profileTestAlloc")
+	}
+	if total != prof.Total {
+		t.Errorf("got total %d, want the sum of the samples %d", prof.Total, total)
+	}
+
+	if prof := readProfileData(path, "cpu", "nanoseconds", dir); prof != nil {
+		t.Errorf("got %+v for the missing sample type, want nil", prof)
+	}
+	if prof := readProfileData(filepath.Join(dir, cpuProfileFile), "cpu", "nanoseconds", dir); prof != nil {
+		t.Errorf("got %+v for the missing file, want nil", prof)
+	}
+}
+
+func TestDecodePprofInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated key", []byte{0x80}},
+		{"truncated varint", []byte{0x08, 0x80}},
+		{"too long varint", []byte{0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"truncated fixed64", []byte{0x09, 1, 2, 3}},
+		{"truncated fixed32", []byte{0x0d, 1}},
+		{"truncated bytes", []byte{0x12, 0x05, 0x01}},
+		{"unknown wire type", []byte{0x0b}},
+		{"invalid sample", []byte{0x12, 0x01, 0x80}},
+		{"invalid packed location ids", []byte{0x12, 0x03, 0x0a, 0x01, 0x80}},
+		{"fixed32 location id", []byte{0x12, 0x05, 0x0d, 1, 2, 3, 4}},
+		{"invalid location", []byte{0x22, 0x01, 0x80}},
+		{"invalid location line", []byte{0x22, 0x03, 0x22, 0x01, 0x80}},
+		{"invalid function", []byte{0x2a, 0x01, 0x80}},
+		{"invalid sample type", []byte{0x0a, 0x01, 0x80}},
+	}
+	for _, test := range tests {
+		if _, err := decodePprof(test.data); err != errInvalidProfile {
+			t.Errorf("%s: got error %v, want %v", test.name, err, errInvalidProfile)
+		}
+	}
+}
+
+func TestProtoVarint(t *testing.T) {
+	tests := []struct {
+		data []byte
+		v    uint64
+		n    int
+	}{
+		{[]byte{0x01}, 1, 1},
+		{[]byte{0xac, 0x02}, 300, 2},
+		{[]byte{0xac, 0x02, 0x01}, 300, 2},
+		{[]byte{0xac}, 0, 0},
+		{nil, 0, 0},
+	}
+	for _, test := range tests {
+		if v, n := protoVarint(test.data); v != test.v || n != test.n {
+			t.Errorf("protoVarint(%x) = %d, %d, want %d, %d", test.data, v, n, test.v, test.n)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errQueueFull = errors.New("Server is busy, please try again later")
+
+// flightGroup collapses concurrent calls with the same key
+// into a single call, and shares its result between the callers
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	data    []byte
+	err     error
+}
+
+// Do executes fn, unless there's already a call in flight for the key,
+// in which case it waits for that call to complete and returns its result.
+// If ctx is done before the call completes, Do returns ctx.Err();
+// the context passed to fn is canceled once all the callers are gone.
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.Background())
+		c = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.run(callCtx, key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.data, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			g.forget(key, c)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, c *flightCall, fn func(ctx context.Context) ([]byte, error)) {
+	c.data, c.err = fn(ctx)
+	c.cancel()
+
+	g.mu.Lock()
+	g.forget(key, c)
+	g.mu.Unlock()
+
+	close(c.done)
+}
+
+// forget removes the call, so that new callers start a new one
+// (the key can already belong to a newer call if this one was canceled)
+func (g *flightGroup) forget(key string, c *flightCall) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// workQueue limits the number of jobs that run concurrently,
+// and the number of jobs that can wait for a free worker
+type workQueue struct {
+	workers chan struct{}
+	pending chan struct{}
+}
+
+func newWorkQueue(workers, queueSize int) *workQueue {
+	return &workQueue{
+		workers: make(chan struct{}, workers),
+		pending: make(chan struct{}, workers+queueSize),
+	}
+}
+
+// Acquire waits for a free worker; it returns errQueueFull without waiting
+// if the queue is already full, or ctx.Err() if ctx is done while waiting
+func (q *workQueue) Acquire(ctx context.Context) error {
+	select {
+	case q.pending <- struct{}{}:
+	default:
+		return errQueueFull
+	}
+
+	select {
+	case q.workers <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		<-q.pending
+		return ctx.Err()
+	}
+}
+
+// Release frees the worker acquired with Acquire
+func (q *workQueue) Release() {
+	<-q.workers
+	<-q.pending
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters waits until the call for the key has n waiters
+func waitForWaiters(t *testing.T, g *flightGroup, key string, n int) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		g.mu.Lock()
+		c := g.calls[key]
+		ok := c != nil && c.waiters == n
+		g.mu.Unlock()
+		if ok {
+			return
+		}
+	}
+	t.Fatalf("the call for %s didn't get %d waiters", key, n)
+}
+
+func TestFlightGroupShared(t *testing.T) {
+	g := &flightGroup{}
+	release := make(chan struct{})
+	calls := 0
+	fn := func(ctx context.Context) ([]byte, error) {
+		calls++
+		<-release
+		return []byte("result"), nil
+	}
+
+	const n = 5
+	results := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, err := g.Do(context.Background(), "key", fn)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = string(data)
+		}(i)
+	}
+	waitForWaiters(t, g, "key", n)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+	for i, r := range results {
+		if r != "result" {
+			t.Errorf("caller %d: got %q, want %q", i, r, "result")
+		}
+	}
+	if len(g.calls) != 0 {
+		t.Errorf("got %d calls in flight after the call, want 0", len(g.calls))
+	}
+}
+
+func TestFlightGroupCancel(t *testing.T) {
+	g := &flightGroup{}
+	started := make(chan context.Context, 1)
+	fn := func(ctx context.Context) ([]byte, error) {
+		started <- ctx
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.Do(ctx1, "key", fn)
+		errs <- err
+	}()
+	callCtx := <-started
+	go func() {
+		_, err := g.Do(ctx2, "key", fn)
+		errs <- err
+	}()
+	waitForWaiters(t, g, "key", 2)
+
+	// the call goes on while there are callers waiting for it
+	cancel1()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("got error %v for the first caller, want %v", err, context.Canceled)
+	}
+	if callCtx.Err() != nil {
+		t.Error("the call was canceled with a caller waiting for it")
+	}
+
+	cancel2()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("got error %v for the second caller, want %v", err, context.Canceled)
+	}
+	if callCtx.Err() == nil {
+		t.Error("the call wasn't canceled when all the callers were gone")
+	}
+}
+
+func TestFlightGroupForget(t *testing.T) {
+	g := &flightGroup{}
+	release := make(chan struct{})
+	oldStarted := make(chan struct{})
+	oldFn := func(ctx context.Context) ([]byte, error) {
+		close(oldStarted)
+		<-release // the canceled call ignores the cancellation for a while
+		return []byte("old"), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := g.Do(ctx, "key", oldFn)
+		errc <- err
+	}()
+	<-oldStarted
+	g.mu.Lock()
+	oldCall := g.calls["key"]
+	g.mu.Unlock()
+	cancel()
+	<-errc
+
+	// a new caller starts a new call while the canceled one is still running
+	newRelease := make(chan struct{})
+	newFn := func(ctx context.Context) ([]byte, error) {
+		<-newRelease
+		return []byte("new"), nil
+	}
+	datac := make(chan []byte, 1)
+	go func() {
+		data, _ := g.Do(context.Background(), "key", newFn)
+		datac <- data
+	}()
+	waitForWaiters(t, g, "key", 1)
+
+	// the stale call doesn't remove the new one when it completes
+	close(release)
+	<-oldCall.done
+	g.mu.Lock()
+	c := g.calls["key"]
+	g.mu.Unlock()
+	if c == nil || c == oldCall {
+		t.Error("the stale call removed the new one")
+	}
+
+	close(newRelease)
+	if data := <-datac; string(data) != "new" {
+		t.Errorf("got %q, want %q", data, "new")
+	}
+}
+
+func TestWorkQueue(t *testing.T) {
+	q := newWorkQueue(1, 1)
+	if err := q.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second job waits for the worker, and the third one is rejected
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- q.Acquire(ctx) }()
+	for start := time.Now(); len(q.pending) < 2; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the second job isn't waiting")
+		}
+	}
+	if err := q.Acquire(context.Background()); err != errQueueFull {
+		t.Errorf("got error %v for the third job, want %v", err, errQueueFull)
+	}
+
+	// the job that stops waiting frees its place in the queue
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("got error %v for the canceled job, want %v", err, context.Canceled)
+	}
+	go func() { errc <- q.Acquire(context.Background()) }()
+	for start := time.Now(); len(q.pending) < 2; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the next job isn't waiting")
+		}
+	}
+
+	q.Release()
+	if err := <-errc; err != nil {
+		t.Errorf("got error %v for the next job, want nil", err)
+	}
+	q.Release()
+	if len(q.workers) != 0 || len(q.pending) != 0 {
+		t.Errorf("got %d busy workers and %d pending jobs after Release, want none", len(q.workers), len(q.pending))
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiKeyHeader is the request header that holds the optional API key;
+// clients with a known API key are rate limited by the key rather than by IP
+const apiKeyHeader = "X-API-Key"
+
+// how often rateLimiter removes idle buckets
+const bucketSweepInterval = 10 * time.Minute
+
+// trustProxy tells whether to take the client IP from X-Forwarded-For
+var trustProxy bool
+
+// apiKeys is the set of known API keys; unknown keys are ignored,
+// so that clients can't get a fresh bucket by making up a new key
+var apiKeys = make(map[string]bool)
+
+// setAPIKeys sets the known API keys from the comma-separated list
+func setAPIKeys(list string) {
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys[key] = true
+		}
+	}
+}
+
+// rateLimiter implements per-client token bucket rate limiting:
+// each client can make up to Burst requests at once, and the bucket
+// is refilled at Rate requests per second
+type rateLimiter struct {
+	Rate  float64
+	Burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// parseRateLimit parses limits like '30/1m' (30 requests per minute);
+// it returns nil for '0' or an empty string (no limit)
+func parseRateLimit(s string) (*rateLimiter, error) {
+	if s == "" || s == "0" {
+		return nil, nil
+	}
+
+	tokens := strings.SplitN(s, "/", 2)
+	if len(tokens) != 2 {
+		return nil, errors.New("rate limit must be in the 'N/duration' format, e.g. '30/1m'")
+	}
+
+	n, err := strconv.Atoi(tokens[0])
+	if err != nil || n < 1 {
+		return nil, errors.New("invalid number of requests in rate limit: " + tokens[0])
+	}
+
+	period, err := time.ParseDuration(tokens[1])
+	if err != nil || period <= 0 {
+		return nil, errors.New("invalid period in rate limit: " + tokens[1])
+	}
+
+	return &rateLimiter{
+		Rate:    float64(n) / period.Seconds(),
+		Burst:   float64(n),
+		buckets: make(map[string]*bucket),
+	}, nil
+}
+
+// Allow takes a token from the client's bucket; if the bucket is empty,
+// it returns false and the time after which the request can be retried
+func (l *rateLimiter) Allow(client string) (bool, time.Duration) {
+	return l.allow(client, time.Now())
+}
+
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.Burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.Burst, b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep removes buckets that have been refilled completely,
+// as they are no different from the new ones
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= l.Burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientID returns the API key if a known one is provided
+// with the request, or client IP address otherwise
+func clientID(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" && apiKeys[key] {
+		return "key:" + key
+	}
+
+	if trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return "ip:" + strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// limitHandler wraps the handler with the rate limiter;
+// if the limiter is nil, the handler is returned as is
+func limitHandler(l *rateLimiter, h http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.Allow(clientID(r)); !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, "Too many requests, please try again in "+strconv.Itoa(seconds)+" seconds", http.StatusTooManyRequests)
+			return
+		}
+		h(w, r)
+	}
+}
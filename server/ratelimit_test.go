@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		limit   string
+		rate    float64
+		burst   float64
+		wantErr bool
+	}{
+		{"", 0, 0, false},
+		{"0", 0, 0, false},
+		{"30/1m", 0.5, 30, false},
+		{"10/2s", 5, 10, false},
+		{"30", 0, 0, true},
+		{"0/1m", 0, 0, true},
+		{"x/1m", 0, 0, true},
+		{"30/0s", 0, 0, true},
+		{"30/minute", 0, 0, true},
+	}
+	for _, test := range tests {
+		l, err := parseRateLimit(test.limit)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: got error %v, want error: %v", test.limit, err, test.wantErr)
+			continue
+		}
+		if l == nil {
+			if test.rate != 0 {
+				t.Errorf("%q: got no limit, want %v/s", test.limit, test.rate)
+			}
+			continue
+		}
+		if l.Rate != test.rate || l.Burst != test.burst {
+			t.Errorf("%q: got rate %v, burst %v, want %v, %v", test.limit, l.Rate, l.Burst, test.rate, test.burst)
+		}
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	l, _ := parseRateLimit("2/1m") // a token every 30 seconds
+	start := time.Now()
+
+	tests := []struct {
+		client  string
+		elapsed time.Duration
+		allowed bool
+		wait    time.Duration
+	}{
+		{"a", 0, true, 0},
+		{"a", 0, true, 0},
+		{"a", 0, false, 30 * time.Second},
+		{"b", 0, true, 0}, // clients have separate buckets
+		{"a", 10 * time.Second, false, 20 * time.Second},
+		{"a", 30 * time.Second, true, 0},
+		{"a", 30 * time.Second, false, 30 * time.Second},
+		{"a", 5 * time.Minute, true, 0}, // the bucket holds Burst tokens at most
+		{"a", 5 * time.Minute, true, 0},
+		{"a", 5 * time.Minute, false, 30 * time.Second},
+	}
+	for i, test := range tests {
+		allowed, wait := l.allow(test.client, start.Add(test.elapsed))
+		if allowed != test.allowed || wait != test.wait {
+			t.Errorf("request %d from %s at %v: got %v, %v, want %v, %v", i, test.client, test.elapsed, allowed, wait, test.allowed, test.wait)
+		}
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l, _ := parseRateLimit("60/1h") // a token every minute
+	start := time.Now()
+	l.allow("idle", start)
+	for i := 0; i < 60; i++ {
+		l.allow("busy", start)
+	}
+	l.lastSweep = start
+
+	// the buckets are only swept every bucketSweepInterval
+	l.allow("new", start.Add(bucketSweepInterval-time.Second))
+	if len(l.buckets) != 3 {
+		t.Errorf("got %d buckets before the sweep, want 3", len(l.buckets))
+	}
+
+	// the full buckets are removed, and the ones being refilled are kept
+	l.allow("busy", start.Add(bucketSweepInterval))
+	for client, want := range map[string]bool{"idle": false, "busy": true, "new": true} {
+		if _, ok := l.buckets[client]; ok != want {
+			t.Errorf("%s: got bucket %v after the sweep, want %v", client, ok, want)
+		}
+	}
+}
+
+func TestLimitHandler(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	if limitHandler(nil, h) == nil {
+		t.Fatal("got no handler without the limiter")
+	}
+
+	// Retry-After is the wait time rounded up to seconds
+	l, _ := parseRateLimit("1/90s")
+	handler := limitHandler(l, h)
+	tests := []struct {
+		code       int
+		retryAfter string
+	}{
+		{http.StatusOK, ""},
+		{http.StatusTooManyRequests, "90"},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/compile", nil)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != test.code || w.Header().Get("Retry-After") != test.retryAfter {
+			t.Errorf("request %d: got %d with Retry-After %q, want %d with %q", i, w.Code, w.Header().Get("Retry-After"), test.code, test.retryAfter)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CompileEvent kinds reported when the program exceeds a resource limit
+const (
+	limitTimeKind   = "limit-time"
+	limitCPUKind    = "limit-cpu"
+	limitMemoryKind = "limit-memory"
+	limitOutputKind = "limit-output"
+)
+
+// sandboxExecArg is the first command-line argument that makes
+// the server binary apply the resource limits to itself and exec
+// the program (see sandboxExec): os/exec can't set rlimits
+// for the child process
+const sandboxExecArg = "-sandbox-exec"
+
+// outOfMemoryMessages are the messages the Go runtime
+// prints when it fails to allocate memory
+var outOfMemoryMessages = []string{
+	"runtime: out of memory",
+	"cannot allocate memory",
+}
+
+// sandboxLimits are the resource limits for programs
+// run by the local backend; zero values mean no limit
+type sandboxLimits struct {
+	// WallTime is the wall-clock time limit for the run
+	// (the -timeout limit for the build and run applies, too)
+	WallTime time.Duration
+
+	// CPUTime is the CPU time limit (RLIMIT_CPU)
+	CPUTime time.Duration
+
+	// Memory is the data segment limit in bytes (RLIMIT_DATA; unlike
+	// RLIMIT_AS, it doesn't count the address space the Go runtime
+	// reserves without using it)
+	Memory int64
+
+	// Output is the limit of the total size of stdout and stderr
+	Output int64
+
+	// FuzzTime is the duration of fuzzing sessions
+	FuzzTime time.Duration
+
+	// NoNetwork tells to run programs in a separate
+	// network namespace without any interfaces
+	NoNetwork bool
+}
+
+// runLimits are the limits for all local backends
+var runLimits = &sandboxLimits{}
+
+// command makes cmd run the program within the sandbox:
+// with the resource limits (where available), and with
+// the temporary files kept in dir, which is removed after the run
+func (l *sandboxLimits) command(cmd *exec.Cmd, dir string) {
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "TMPDIR="+dir)
+	l.wrap(cmd)
+}
+
+// checkFuzzTime checks that fuzzing sessions end before the build
+// and run timeout and the wall-clock time limit stop them
+func (l *sandboxLimits) checkFuzzTime(timeout time.Duration) error {
+	if l.FuzzTime <= 0 {
+		return errors.New("fuzzing time must be positive")
+	}
+	if l.FuzzTime >= timeout {
+		return fmt.Errorf("fuzzing time %v must be less than the timeout %v", l.FuzzTime, timeout)
+	}
+	if l.WallTime > 0 && l.FuzzTime >= l.WallTime {
+		return fmt.Errorf("fuzzing time %v must be less than the run time limit %v", l.FuzzTime, l.WallTime)
+	}
+	return nil
+}
+
+// cpuSeconds returns the RLIMIT_CPU value (rounded up to seconds)
+func (l *sandboxLimits) cpuSeconds() int64 {
+	return int64((l.CPUTime + time.Second - 1) / time.Second)
+}
+
+// limitEvent returns the event reporting the exceeded limit
+// for the finished run: the output limit is checked by the caller,
+// and the wall-clock time limit is reported if the run was stopped
+// after wallTimeout
+func (l *sandboxLimits) limitEvent(state *os.ProcessState, events []*CompileEvent, wallTimeout bool) *CompileEvent {
+	if wallTimeout {
+		return &CompileEvent{
+			Kind:    limitTimeKind,
+			Message: fmt.Sprintf("Program exceeded the time limit (%v)\n", l.WallTime),
+		}
+	}
+
+	if state == nil || state.Success() {
+		return nil
+	}
+
+	if l.CPUTime > 0 && state.UserTime()+state.SystemTime() >= time.Duration(l.cpuSeconds())*time.Second {
+		return &CompileEvent{
+			Kind:    limitCPUKind,
+			Message: fmt.Sprintf("Program exceeded the CPU time limit (%v)\n", l.CPUTime),
+		}
+	}
+
+	if l.Memory > 0 {
+		for _, evt := range events {
+			for _, msg := range outOfMemoryMessages {
+				if strings.Contains(evt.Message, msg) {
+					return &CompileEvent{
+						Kind:    limitMemoryKind,
+						Message: fmt.Sprintf("Program exceeded the memory limit (%s)\n", formatBytes(l.Memory)),
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// outputLimitEvent returns the event reporting the exceeded output limit
+func (l *sandboxLimits) outputLimitEvent() *CompileEvent {
+	return &CompileEvent{
+		Kind:    limitOutputKind,
+		Message: fmt.Sprintf("Program output exceeded the limit (%s)\n", formatBytes(l.Output)),
+	}
+}
+
+// hasLoadDependentLimitEvent returns true if the run was stopped
+// by a time limit, which depends on server load
+func hasLoadDependentLimitEvent(resp *CompileResponse) bool {
+	for _, evt := range resp.Events {
+		if evt.Kind == limitTimeKind || evt.Kind == limitCPUKind {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBytes returns the human-readable size, e.g. '512 MB'
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
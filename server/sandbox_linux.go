@@ -0,0 +1,121 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// check disables the sandbox features that aren't available
+// on this system (e.g. unprivileged user namespaces may be disabled)
+func (l *sandboxLimits) check() {
+	if !l.NoNetwork {
+		return
+	}
+
+	self, err := os.Executable()
+	if err == nil {
+		cmd := exec.Command(self, "-h")
+		cmd.SysProcAttr = l.sysProcAttr()
+		err = cmd.Run()
+	}
+	if err != nil {
+		log.Printf("Network namespaces are not available (%v), programs will have network access", err)
+		l.NoNetwork = false
+	}
+}
+
+func (l *sandboxLimits) sysProcAttr() *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{
+		// run in a separate process group,
+		// so that all the processes can be killed at once
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if l.NoNetwork {
+		// a user namespace allows creating the network namespace
+		// without privileges; the user ID stays the same
+		attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	}
+	return attr
+}
+
+// wrap makes cmd run via sandboxExec, which applies the rlimits
+func (l *sandboxLimits) wrap(cmd *exec.Cmd) {
+	cmd.SysProcAttr = l.sysProcAttr()
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+
+	if cmd.Err != nil || (l.CPUTime == 0 && l.Memory == 0) {
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		log.Printf("Failed to apply resource limits: %v", err)
+		return
+	}
+
+	args := []string{self, sandboxExecArg,
+		strconv.FormatInt(l.cpuSeconds(), 10),
+		strconv.FormatInt(l.Memory, 10),
+		cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = self
+}
+
+// killProcessGroup kills the process started by cmd,
+// and all the processes it has started
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// sandboxExec applies the rlimits and executes the program; args are
+// the CPU time limit in seconds, the data segment size limit in bytes
+// (RLIMIT_DATA), and the program with its arguments
+func sandboxExec(args []string) {
+	if len(args) < 3 {
+		sandboxFail(fmt.Errorf("invalid arguments: %q", args))
+	}
+
+	cpu, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		sandboxFail(err)
+	}
+	if cpu > 0 {
+		// the soft limit sends SIGXCPU, which Go programs ignore,
+		// so the hard limit (SIGKILL) is what actually stops them
+		if err := syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: cpu, Max: cpu + 1}); err != nil {
+			sandboxFail(err)
+		}
+	}
+
+	mem, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		sandboxFail(err)
+	}
+	if mem > 0 {
+		if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: mem, Max: mem}); err != nil {
+			sandboxFail(err)
+		}
+	}
+
+	sandboxFail(syscall.Exec(args[2], args[2:], os.Environ()))
+}
+
+func sandboxFail(err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(127)
+}
@@ -0,0 +1,31 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"log"
+	"os/exec"
+)
+
+// check disables the sandbox features that aren't available
+// on this system: only the wall-clock time and output limits
+// are supported on platforms other than Linux
+func (l *sandboxLimits) check() {
+	if l.CPUTime != 0 || l.Memory != 0 || l.NoNetwork {
+		log.Printf("CPU time, memory and network limits are only supported on Linux")
+	}
+	l.CPUTime = 0
+	l.Memory = 0
+	l.NoNetwork = false
+}
+
+func (l *sandboxLimits) wrap(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return nil
+}
+
+func sandboxExec(args []string) {
+	log.Fatal("Resource limits are only supported on Linux")
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckFuzzTime(t *testing.T) {
+	tests := []struct {
+		fuzzTime, wallTime, timeout time.Duration
+		ok                          bool
+	}{
+		{5 * time.Second, 0, 10 * time.Second, true},
+		{5 * time.Second, 8 * time.Second, 10 * time.Second, true},
+		{0, 0, 10 * time.Second, false},
+		{10 * time.Second, 0, 10 * time.Second, false},
+		{5 * time.Second, 5 * time.Second, 10 * time.Second, false},
+		{15 * time.Second, 20 * time.Second, 10 * time.Second, false},
+	}
+	for _, test := range tests {
+		l := &sandboxLimits{FuzzTime: test.fuzzTime, WallTime: test.wallTime}
+		if err := l.checkFuzzTime(test.timeout); (err == nil) != test.ok {
+			t.Errorf("fuzz time %v, run time limit %v, timeout %v: got error %v, want ok=%v",
+				test.fuzzTime, test.wallTime, test.timeout, err, test.ok)
+		}
+	}
+}
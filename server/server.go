@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -9,10 +10,8 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -24,6 +23,8 @@ const userAgent = "goplay.space/1.0 (https://goplay.space/)"
 // https://github.com/golang/playground/blob/master/app/goplay/share.go
 const maxSnippetSize = 64 * 1024
 
+var errSnippetTooLarge = errors.New("Snippet is too large")
+
 // FmtResponse is the response returned from
 // upstream play.golang.org/fmt request
 type FmtResponse struct {
@@ -31,6 +32,22 @@ type FmtResponse struct {
 	Error string
 }
 
+// VetResponse is the response returned from
+// upstream play.golang.org/vet request
+type VetResponse struct {
+	Errors string
+}
+
+// setVet sets the vet findings of the response
+// (vet is nil if it wasn't run)
+func (resp *CompileResponse) setVet(vet *VetResponse) {
+	if vet == nil {
+		return
+	}
+	resp.VetErrors = vet.Errors
+	resp.VetOK = vet.Errors == ""
+}
+
 // CompileEvent represents individual
 // event record in CompileResponse
 type CompileEvent struct {
@@ -45,6 +62,39 @@ type CompileResponse struct {
 	Body   *string
 	Events []*CompileEvent
 	Errors string
+
+	// Status is the program exit code
+	Status int
+
+	// IsTest is true if the program was run in test mode,
+	// and TestsFailed is the number of failed tests
+	IsTest      bool
+	TestsFailed int
+
+	// VetErrors contains go vet findings, if vet was run
+	VetErrors string `json:",omitempty"`
+	VetOK     bool   `json:",omitempty"`
+
+	// Tests contains the results of individual tests,
+	// benchmarks and examples in test mode
+	Tests []*TestResult `json:",omitempty"`
+
+	// BuildOnly is true if the program was only built
+	// (e.g. for another GOOS/GOARCH), but not run
+	BuildOnly bool `json:",omitempty"`
+
+	// Profile contains the CPU and heap profiles of the run,
+	// if requested
+	Profile *Profile `json:",omitempty"`
+
+	// Trace contains the execution trace of the run, if requested
+	Trace *Trace `json:",omitempty"`
+
+	// Coverage contains the test coverage in test mode
+	Coverage *Coverage `json:",omitempty"`
+
+	// Fuzz contains the result of the fuzzing session, if requested
+	Fuzz *FuzzResult `json:",omitempty"`
 }
 
 func gzPath(path string) string {
@@ -52,8 +102,37 @@ func gzPath(path string) string {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == sandboxExecArg {
+		sandboxExec(os.Args[2:])
+		return
+	}
+
 	port := flag.Int("p", 8080, "port to listen at")
 	help := flag.Bool("h", false, "show this help")
+	upstreamList := flag.String("upstream", "https://play.golang.org", "comma-separated list of upstream playground URLs, tried in order")
+	backendName := flag.String("backend", "upstream", "code execution backend: 'upstream' or 'local'")
+	goBin := flag.String("go", "go", "path to the go binary used by the local backend")
+	runTimeout := flag.Duration("timeout", 10*time.Second, "build and run timeout for the local backend")
+	flag.DurationVar(&runLimits.WallTime, "run-timeout", 0, "wall-clock time limit for running programs with the local backend (0 to only use -timeout)")
+	flag.DurationVar(&runLimits.CPUTime, "run-cpu", 10*time.Second, "CPU time limit for programs run with the local backend (0 to disable; Linux only)")
+	flag.Int64Var(&runLimits.Memory, "run-memory", 512*1024*1024, "memory limit in bytes for programs run with the local backend (0 to disable; Linux only)")
+	flag.Int64Var(&runLimits.Output, "run-output", 1024*1024, "output size limit in bytes for programs run with the local backend (0 to disable)")
+	flag.DurationVar(&runLimits.FuzzTime, "run-fuzztime", 5*time.Second, "duration of fuzzing sessions with the local backend (must be less than -timeout and -run-timeout)")
+	runNetwork := flag.Bool("run-network", false, "allow network access for programs run with the local backend (Linux only; otherwise they are always allowed)")
+	versionList := flag.String("versions", "", "comma-separated list of Go versions in the 'label=target' format, where target is an upstream URL or a local GOROOT, e.g. 'go1.22=https://play.golang.org,gotip=/opt/gotip' (the first one is the default; overrides -backend)")
+	storeName := flag.String("store", "upstream", "snippet store: 'upstream', 'local' or 'fallback' (local with upstream fallback)")
+	storeDir := flag.String("store-dir", "snippets", "directory for the local snippet store")
+	cacheSize := flag.Int("cache-size", 16*1024*1024, "in-memory compile cache size in bytes (0 to disable caching)")
+	cacheTTL := flag.Duration("cache-ttl", 24*time.Hour, "compile cache entry lifetime")
+	cacheDir := flag.String("cache-dir", "", "directory for the on-disk compile cache (disabled if empty)")
+	cacheDirSize := flag.Int64("cache-dir-size", 256*1024*1024, "on-disk compile cache size in bytes")
+	flag.BoolVar(&cacheLocalRuns, "cache-local", false, "cache the results of local backend runs (their output may depend on the time, random numbers or scheduling)")
+	workers := flag.Int("workers", 4, "maximum number of concurrently running compile requests")
+	queueSize := flag.Int("queue", 100, "maximum number of compile requests waiting for a free worker")
+	compileLimit := flag.String("compile-limit", "30/1m", "per-client /compile rate limit, e.g. '30/1m' (0 to disable)")
+	shareLimit := flag.String("share-limit", "10/1m", "per-client /share rate limit, e.g. '10/1m' (0 to disable)")
+	flag.BoolVar(&trustProxy, "trust-proxy", false, "take client IP addresses from the X-Forwarded-For header")
+	apiKeyList := flag.String("api-keys", "", "comma-separated list of API keys; clients sending a known key in the X-API-Key header are rate limited by the key instead of their IP address")
 
 	flag.Parse()
 
@@ -62,11 +141,83 @@ func main() {
 		return
 	}
 
+	runLimits.NoNetwork = !*runNetwork
+	runLimits.check()
+	if err := runLimits.checkFuzzTime(*runTimeout); err != nil {
+		log.Fatalf("Invalid -run-fuzztime: %v", err)
+	}
+
+	upstreamURLs = parseUpstreamList(*upstreamList)
+	if len(upstreamURLs) == 0 {
+		log.Fatal("No upstream URLs provided")
+	}
+
+	if *versionList != "" {
+		if err := parseVersions(*versionList, *runTimeout); err != nil {
+			log.Fatalf("Invalid -versions: %v", err)
+		}
+	} else {
+		switch *backendName {
+		case "upstream":
+			addVersion("", &upstreamBackend{})
+		case "local":
+			addVersion("", &localBackend{
+				GoBin:   *goBin,
+				Timeout: *runTimeout,
+			})
+		default:
+			log.Fatalf("Unknown backend: %s", *backendName)
+		}
+
+		log.Printf("Using %s backend", *backendName)
+	}
+
+	switch *storeName {
+	case "upstream":
+		store = &upstreamStore{}
+	case "local":
+		store = &fileStore{Dir: *storeDir}
+	case "fallback":
+		store = &fallbackStore{
+			Local:    &fileStore{Dir: *storeDir},
+			Upstream: &upstreamStore{},
+		}
+	default:
+		log.Fatalf("Unknown store: %s", *storeName)
+	}
+
+	log.Printf("Using %s snippet store", *storeName)
+
+	if *cacheSize > 0 {
+		cache = newCompileCache(*cacheSize, *cacheDirSize, *cacheTTL, *cacheDir)
+	}
+
+	if *workers < 1 {
+		log.Fatal("Number of workers must be positive")
+	}
+	compileQueue = newWorkQueue(*workers, *queueSize)
+
+	setAPIKeys(*apiKeyList)
+	compileLimiter, err := parseRateLimit(*compileLimit)
+	if err != nil {
+		log.Fatalf("Invalid -compile-limit: %v", err)
+	}
+	shareLimiter, err := parseRateLimit(*shareLimit)
+	if err != nil {
+		log.Fatalf("Invalid -share-limit: %v", err)
+	}
+
 	log.Printf("Listening on http://localhost:%d/", *port)
 
 	http.Handle("/", http.FileServer(http.Dir(staticDir)))
-	http.HandleFunc("/compile", compileHandler)
-	http.HandleFunc("/share", shareHandler)
+	http.HandleFunc("/compile", limitHandler(compileLimiter, compileHandler))
+	http.HandleFunc("/api/v2/compile", limitHandler(compileLimiter, compileV2Handler))
+	http.HandleFunc("/api/v2/compile/stream", limitHandler(compileLimiter, compileStreamHandler))
+	http.HandleFunc("/api/v2/asm", limitHandler(compileLimiter, asmHandler))
+	http.HandleFunc("/api/v2/diagnostics", limitHandler(compileLimiter, diagnosticsHandler))
+	http.HandleFunc("/api/v2/ssa", limitHandler(compileLimiter, ssaHandler))
+	http.HandleFunc("/api/v2/versions", versionsHandler)
+	http.HandleFunc("/share", limitHandler(shareLimiter, shareHandler))
 	http.HandleFunc("/load", loadHandler)
 
 	if _, err := os.Stat(gzPath("/client.js")); err == nil {
@@ -79,54 +230,34 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+strconv.Itoa(*port), nil))
 }
 
-func doRequest(method, url, contentType string, body io.Reader) ([]byte, error) {
-	req, err := http.NewRequest(method, url, body)
+func doRequest(ctx context.Context, method, url, contentType string, body io.Reader) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	req.Header.Add("Content-Type", contentType)
 	req.Header.Add("User-Agent", userAgent)
 	response, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 
 	var bodyBytes bytes.Buffer
 	_, err = io.Copy(&bodyBytes, io.LimitReader(response.Body, maxSnippetSize+1))
 	response.Body.Close()
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	if bodyBytes.Len() > maxSnippetSize {
-		return nil, errors.New("Snippet is too large")
+		return 0, nil, errSnippetTooLarge
 	}
-	return bodyBytes.Bytes(), nil
-}
-
-func postForm(url string, data url.Values) ([]byte, error) {
-	return doRequest("POST", url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
-}
-
-func runShare(body io.Reader) ([]byte, error) {
-	return doRequest("POST", "https://play.golang.org/share", "text/plain", body)
-}
-
-func runImports(body *string) ([]byte, error) {
-	form := url.Values{}
-	form.Add("imports", "true")
-	form.Add("body", *body)
-
-	return postForm("https://play.golang.org/fmt", form)
-}
-
-func runCompile(body *string) ([]byte, error) {
-	form := url.Values{}
-	form.Add("body", *body)
-	form.Add("version", "2")
-
-	return postForm("https://play.golang.org/compile", form)
+	return response.StatusCode, bodyBytes.Bytes(), nil
 }
 
+// compileHandler implements the play.golang.org-compatible /compile API;
+// it is a thin wrapper over the /api/v2/compile implementation.
+// Like on play.golang.org, the code is only vetted if the request
+// has withVet=true (a query parameter here, as the body is the source code).
 func compileHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -141,63 +272,35 @@ func compileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body := string(bodyBytes)
-
-	bodyBytes, err = runImports(&body)
-	if err != nil {
-		log.Printf("runImports() error: %v", err)
-		http.Error(w, "Failed to format source code", http.StatusInternalServerError)
-		return
-	}
-
-	fmtResponse := FmtResponse{}
-
-	err = json.Unmarshal(bodyBytes, &fmtResponse)
-	if err != nil {
-		log.Printf("fmtResponse unmarshal error: %v", err)
-		http.Error(w, "Failed to decode upstream server response", http.StatusInternalServerError)
-		return
-	}
-
-	if fmtResponse.Error != "" {
-		w.Write(bodyBytes)
-		return
-	}
-
-	bodyUpdated := fmtResponse.Body != body
-
-	bodyBytes, err = runCompile(&fmtResponse.Body)
+	result, cached, err := processCompileRequest(r.Context(), &CompileRequest{
+		Body: string(bodyBytes),
+		Vet:  r.URL.Query().Get("withVet") == "true",
+	})
 	if err != nil {
-		log.Printf("runCompile() error: %v", err)
-		http.Error(w, "Failed to compile source code", http.StatusInternalServerError)
+		writeCompileError(w, err)
 		return
 	}
 
-	if !bodyUpdated {
-		w.Write(bodyBytes)
-		return
+	if result.Format != nil && result.Format.Error != "" {
+		bodyBytes, err = json.Marshal(FmtResponse{Error: result.Format.Error})
+	} else {
+		compileResponse := *result.Run
+		compileResponse.Body = result.Body
+		compileResponse.setVet(result.Vet)
+		bodyBytes, err = json.Marshal(compileResponse)
 	}
-
-	// return a new payload with the formatted body
-
-	compileResponse := CompileResponse{}
-
-	err = json.Unmarshal(bodyBytes, &compileResponse)
-	if err != nil {
-		log.Printf("compileResponse unmarshal error: %v", err)
-		http.Error(w, "Failed to decode upstream server response", http.StatusInternalServerError)
-		return
-	}
-
-	compileResponse.Body = &fmtResponse.Body
-
-	bodyBytes, err = json.Marshal(compileResponse)
 	if err != nil {
 		log.Printf("compileResponse marshal error: %v", err)
 		http.Error(w, "Failed to encode data", http.StatusInternalServerError)
 		return
 	}
 
+	setCacheHeader(w, cached)
+	writeJSONBytes(w, bodyBytes)
+}
+
+func writeJSONBytes(w http.ResponseWriter, bodyBytes []byte) {
+	w.Header().Set("Content-Type", "application/json")
 	w.Write(bodyBytes)
 }
 
@@ -216,13 +319,24 @@ func shareHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bodyBytes, err := runShare(r.Body)
+	bodyBytes, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSnippetSize+1))
 	if err != nil {
+		http.Error(w, "Failed to read request data", http.StatusInternalServerError)
+		return
+	}
+	if len(bodyBytes) > maxSnippetSize {
+		http.Error(w, "Snippet is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	id, err := store.Put(bodyBytes)
+	if err != nil {
+		log.Printf("Put() error: %v", err)
 		http.Error(w, "Failed to send share request", http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(bodyBytes)
+	w.Write([]byte(id))
 }
 
 func loadHandler(w http.ResponseWriter, r *http.Request) {
@@ -233,20 +347,14 @@ func loadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := http.Get("https://play.golang.org/p/" + r.URL.RawQuery + ".go")
-	if err != nil {
-		http.Error(w, "Failed to load snippet", http.StatusInternalServerError)
+	bodyBytes, err := store.Get(r.URL.RawQuery)
+	if err == errSnippetNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	defer response.Body.Close()
-
-	bodyBytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		http.Error(w, "Failed to read data", http.StatusInternalServerError)
-	}
-
-	if response.StatusCode != http.StatusOK {
-		http.Error(w, string(bodyBytes), response.StatusCode)
+		log.Printf("Get() error: %v", err)
+		http.Error(w, "Failed to load snippet", http.StatusInternalServerError)
 		return
 	}
 
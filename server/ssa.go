@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+)
+
+// SSARequest is the /api/v2/ssa request payload
+type SSARequest struct {
+	Body string
+
+	// Version is the Go version label;
+	// the default version is used if it is empty
+	Version string
+
+	// Func is the GOSSAFUNC value, e.g. 'main.main',
+	// 'util.(*T).Get' (a package name qualifies the function)
+	Func string
+}
+
+// SSAResponse is the /api/v2/ssa response payload
+type SSAResponse struct {
+	// Errors contains the build errors, if any
+	Errors string
+
+	// HTML is the ssa.html page generated by the compiler
+	HTML string
+}
+
+// ssaDumper is implemented by backends that can show
+// the SSA form of a function
+type ssaDumper interface {
+	DumpSSA(ctx context.Context, body, fn string) (*SSAResponse, error)
+}
+
+var (
+	// ssaFuncR matches valid GOSSAFUNC values
+	ssaFuncR = regexp.MustCompile(`^[\pL\pN_./()*]+$`)
+
+	// ssaDumpedR matches the compiler message about the written file, e.g.
+	// 'dumped SSA for main,1 to ./ssa.html'
+	ssaDumpedR = regexp.MustCompile(`(?m)^dumped SSA for .* to (.+)$`)
+)
+
+// ssaHandler implements the /api/v2/ssa API
+func ssaHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &SSARequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxSnippetSize)).Decode(req); err != nil {
+		http.Error(w, "Failed to decode request data", http.StatusBadRequest)
+		return
+	}
+	if !ssaFuncR.MatchString(req.Func) {
+		http.Error(w, "Invalid function name", http.StatusBadRequest)
+		return
+	}
+
+	version, b, err := backendFor(req.Version)
+	if err != nil {
+		writeCompileError(w, err)
+		return
+	}
+	d, ok := b.(ssaDumper)
+	if !ok {
+		writeCompileError(w, errSSANotSupported)
+		return
+	}
+	req.Version = version
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		http.Error(w, "Failed to encode data", http.StatusInternalServerError)
+		return
+	}
+	key := cacheKey("ssa:" + string(reqBytes))
+
+	writeAnalysisResult(w, r, key, func(ctx context.Context) (interface{}, bool, error) {
+		resp, err := d.DumpSSA(ctx, req.Body, req.Func)
+		if err != nil {
+			return nil, false, err
+		}
+		return resp, resp.Errors != timeoutErrorMessage, nil
+	})
+}
+
+// DumpSSA implements the ssaDumper interface
+func (b *localBackend) DumpSSA(ctx context.Context, body, fn string) (*SSAResponse, error) {
+	dir, err := b.prepare(body, progFile)
+	if err == errInvalidFileName {
+		return &SSAResponse{Errors: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+
+	// the go command doesn't cache builds with GOSSAFUNC set,
+	// so the file is always written
+	out, err := b.goCommand(ctx, dir, []string{"GOSSAFUNC=" + fn}, "build", "-o", os.DevNull, "./...")
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return &SSAResponse{Errors: timeoutErrorMessage}, nil
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+		return &SSAResponse{Errors: out}, nil
+	}
+
+	m := ssaDumpedR.FindStringSubmatch(out)
+	if m == nil {
+		return &SSAResponse{Errors: fmt.Sprintf("Function %s not found", fn)}, nil
+	}
+	path := filepath.FromSlash(m[1])
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	html, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SSAResponse{HTML: string(html)}, nil
+}
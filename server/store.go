@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// salt is mixed into snippet hashes; the ID generation
+// algorithm mirrors the one used by play.golang.org
+const salt = "[replace this with something unique]"
+
+// snippetIDLength is the length of generated snippet IDs
+// (the same as on play.golang.org)
+const snippetIDLength = 11
+
+var errSnippetNotFound = errors.New("Snippet not found")
+
+var snippetIDR = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// Store is the interface implemented by snippet storages
+type Store interface {
+	// Put saves the snippet and returns its ID
+	Put(body []byte) (string, error)
+
+	// Get returns the snippet by its ID,
+	// or errSnippetNotFound if there's no such snippet
+	Get(id string) ([]byte, error)
+}
+
+// store is the Store instance used by shareHandler and loadHandler
+var store Store
+
+// snippetHash returns the URL-safe hash of the snippet;
+// snippet IDs are its prefixes
+func snippetHash(body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(salt))
+	h.Write(body)
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// snippetID returns the prefix of the hash that is at least n characters
+// long; like on play.golang.org, it is extended while it ends with '_',
+// since web sites don't always linkify trailing underscores
+func snippetID(hash string, n int) string {
+	for n < len(hash) && hash[n-1] == '_' {
+		n++
+	}
+	return hash[:n]
+}
+
+// isValidSnippetID returns true if the ID is safe to use as a file name
+func isValidSnippetID(id string) bool {
+	return snippetIDR.MatchString(id)
+}
+
+// upstreamStore keeps snippets on play.golang.org
+// (or the servers listed in upstreamURLs)
+type upstreamStore struct{}
+
+// Put implements the Store interface
+func (s *upstreamStore) Put(body []byte) (string, error) {
+	status, bodyBytes, err := upstreamRequest("POST", "/share", "text/plain", body)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", errors.New(string(bodyBytes))
+	}
+	return string(bodyBytes), nil
+}
+
+// Get implements the Store interface
+func (s *upstreamStore) Get(id string) ([]byte, error) {
+	if !isValidSnippetID(id) {
+		return nil, errSnippetNotFound
+	}
+
+	status, bodyBytes, err := upstreamRequest("GET", "/p/"+id+".go", "text/plain", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusNotFound {
+		return nil, errSnippetNotFound
+	}
+
+	if status != http.StatusOK {
+		return nil, errors.New(string(bodyBytes))
+	}
+	return bodyBytes, nil
+}
+
+// fileStore keeps snippets as individual files in a local directory
+type fileStore struct {
+	Dir string
+}
+
+func (s *fileStore) path(id string) string {
+	return filepath.Join(s.Dir, id+".go")
+}
+
+// save writes the snippet under the given ID
+func (s *fileStore) save(id string, body []byte) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that
+	// incomplete snippets are never served
+	f, err := ioutil.TempFile(s.Dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.path(id))
+}
+
+// Put implements the Store interface. If the ID is taken
+// by a different snippet, a longer prefix of the hash is used.
+func (s *fileStore) Put(body []byte) (string, error) {
+	hash := snippetHash(body)
+	for n := snippetIDLength; n <= len(hash); n++ {
+		id := snippetID(hash, n)
+		saved, err := ioutil.ReadFile(s.path(id))
+		if os.IsNotExist(err) {
+			return id, s.save(id, body)
+		}
+		if err != nil {
+			return "", err
+		}
+		if bytes.Equal(saved, body) {
+			return id, nil // already saved
+		}
+	}
+	return "", errors.New("Snippet ID collision")
+}
+
+// Get implements the Store interface
+func (s *fileStore) Get(id string) ([]byte, error) {
+	if !isValidSnippetID(id) {
+		return nil, errSnippetNotFound
+	}
+	bodyBytes, err := ioutil.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, errSnippetNotFound
+	}
+	return bodyBytes, err
+}
+
+// fallbackStore saves snippets locally, but loads the ones
+// it doesn't have from the upstream store (and caches them)
+type fallbackStore struct {
+	Local    *fileStore
+	Upstream Store
+}
+
+// Put implements the Store interface
+func (s *fallbackStore) Put(body []byte) (string, error) {
+	return s.Local.Put(body)
+}
+
+// Get implements the Store interface
+func (s *fallbackStore) Get(id string) ([]byte, error) {
+	bodyBytes, err := s.Local.Get(id)
+	if err != errSnippetNotFound || !isValidSnippetID(id) {
+		return bodyBytes, err
+	}
+
+	bodyBytes, err = s.Upstream.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Local.save(id, bodyBytes); err != nil {
+		log.Printf("Failed to cache snippet %s: %v", id, err)
+	}
+	return bodyBytes, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// streamer is implemented by backends that report the program output
+// as it is produced (see RunOptions.OnEvent)
+type streamer interface {
+	streamsOutput()
+}
+
+// eventStream writes server-sent events to the response
+type eventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventStream writes the event stream headers
+func newEventStream(w http.ResponseWriter, flusher http.Flusher, cached bool) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	setCacheHeader(w, cached)
+	return &eventStream{w: w, flusher: flusher}
+}
+
+// Send sends the JSON-encoded data as the event of the given type
+func (s *eventStream) Send(event string, data interface{}) {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("%s event marshal error: %v", event, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, dataBytes)
+	s.flusher.Flush()
+}
+
+// compileStreamHandler implements the /api/v2/compile/stream API.
+// It accepts the same request as /api/v2/compile, and responds with
+// server-sent events: 'output' events with CompileEvent records
+// as the program produces them, followed by a single 'result' event
+// with the CompileResult, or an 'error' event with the error message.
+// Fuzzing sessions also report their progress with 'fuzz' events
+// with FuzzProgress records. Closing the connection stops the program.
+//
+// Cached results are sent right away; streamed runs are not deduplicated,
+// as each client gets its own live output. Backends that can't stream
+// the output only send the 'result' event, and their runs are shared
+// with identical requests (see processCompileRequest).
+func compileStreamHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	req, ok := decodeCompileRequest(w, r)
+	if !ok {
+		return
+	}
+
+	key, b, err := requestKey(req)
+	if err != nil {
+		writeCompileError(w, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if _, ok := b.(streamer); !ok {
+		result, cached, err := processCompileRequest(r.Context(), req)
+		if err != nil {
+			writeCompileError(w, err)
+			return
+		}
+		newEventStream(w, flusher, cached).Send("result", result)
+		return
+	}
+
+	bodyBytes, cached := cache.Get(key)
+	if !cached {
+		if err := compileQueue.Acquire(r.Context()); err != nil {
+			writeCompileError(w, err)
+			return
+		}
+		defer compileQueue.Release()
+	}
+
+	stream := newEventStream(w, flusher, cached)
+	if cached {
+		stream.Send("result", json.RawMessage(bodyBytes))
+		return
+	}
+
+	result, err := runCompileRequest(r.Context(), b, req, func(evt *CompileEvent) {
+		stream.Send("output", evt)
+	}, func(p *FuzzProgress) {
+		stream.Send("fuzz", p)
+	})
+	if err != nil {
+		if r.Context().Err() == nil {
+			stream.Send("error", err.Error())
+		}
+		return
+	}
+
+	if _, err := cacheResult(key, b, result); err != nil {
+		stream.Send("error", err.Error())
+		return
+	}
+	stream.Send("result", result)
+}
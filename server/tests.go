@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestResult is the result of a single test, benchmark or example
+type TestResult struct {
+	// Name is the test name; subtests have names like 'TestFoo/bar'
+	Name string
+
+	// Kind is 'test', 'benchmark', 'example' or 'fuzz'
+	Kind string
+
+	// Status is 'pass', 'fail' or 'skip'
+	Status string
+
+	// Elapsed is the test duration in seconds
+	Elapsed float64
+
+	// Output is the test output without the '=== RUN' and '--- PASS'
+	// framing lines
+	Output string
+}
+
+// testKinds maps test function name prefixes to test kinds
+var testKinds = []struct {
+	prefix string
+	kind   string
+}{
+	{"Test", "test"},
+	{"Benchmark", "benchmark"},
+	{"Example", "example"},
+	{"Fuzz", "fuzz"},
+}
+
+// testKind returns the kind of the test by its name
+// (the rules are the same as in 'go test')
+func testKind(name string) string {
+	name = strings.SplitN(name, "/", 2)[0]
+	for _, k := range testKinds {
+		if !strings.HasPrefix(name, k.prefix) {
+			continue
+		}
+		if len(name) == len(k.prefix) {
+			return k.kind
+		}
+		r, _ := utf8.DecodeRuneInString(name[len(k.prefix):])
+		if !unicode.IsLower(r) {
+			return k.kind
+		}
+	}
+	return ""
+}
+
+// testResults collects results of tests in the order they are started
+type testResults struct {
+	results []*TestResult
+	byName  map[string]*TestResult
+}
+
+func newTestResults() *testResults {
+	return &testResults{byName: make(map[string]*TestResult)}
+}
+
+func (tr *testResults) get(name string) *TestResult {
+	if r, ok := tr.byName[name]; ok {
+		return r
+	}
+	r := &TestResult{Name: name, Kind: testKind(name)}
+	tr.byName[name] = r
+	tr.results = append(tr.results, r)
+	return r
+}
+
+func (tr *testResults) output(name, line string) {
+	if name == "" || isTestFrameLine(line) {
+		return
+	}
+	tr.get(name).Output += line
+}
+
+func (tr *testResults) finish(name, status string, elapsed float64) {
+	if name == "" {
+		return
+	}
+	r := tr.get(name)
+	r.Status = status
+	r.Elapsed = elapsed
+}
+
+// Results returns the collected results; tests that didn't report
+// their status (benchmarks don't) get the status of the whole run
+// (based on the exit code of the test binary)
+func (tr *testResults) Results(exitStatus int) []*TestResult {
+	status := "pass"
+	if exitStatus != 0 {
+		status = "fail"
+	}
+	for _, r := range tr.results {
+		if r.Status == "" {
+			r.Status = status
+		}
+	}
+	return tr.results
+}
+
+// isTestFrameLine returns true for the '=== RUN', '--- PASS'
+// and similar lines that delimit the output of individual tests
+func isTestFrameLine(line string) bool {
+	line = strings.TrimLeft(line, " ")
+	return strings.HasPrefix(line, "=== ") ||
+		strings.HasPrefix(line, "--- PASS: ") ||
+		strings.HasPrefix(line, "--- FAIL: ") ||
+		strings.HasPrefix(line, "--- SKIP: ")
+}
+
+// testEvent is a single record of 'go test -json' output
+type testEvent struct {
+	Action  string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// testJSONWriter decodes 'go test -json' (test2json) output,
+// collecting test results, and passes the plain test output
+// through to the underlying writer
+type testJSONWriter struct {
+	out   io.Writer
+	buf   []byte
+	tests *testResults
+}
+
+func newTestJSONWriter(out io.Writer) *testJSONWriter {
+	return &testJSONWriter{out: out, tests: newTestResults()}
+}
+
+// Write implements the io.Writer interface
+func (w *testJSONWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i == -1 {
+			break
+		}
+		line := w.buf[:i+1]
+		w.buf = w.buf[i+1:]
+		if err := w.processLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *testJSONWriter) processLine(line []byte) error {
+	evt := &testEvent{}
+	if err := json.Unmarshal(line, evt); err != nil {
+		// not a JSON record (e.g. a message from the test2json itself)
+		_, err := w.out.Write(line)
+		return err
+	}
+
+	// make test file names look like regular ones (see goCommand)
+	evt.Output = strings.Replace(evt.Output, testFile, progFile, -1)
+
+	switch evt.Action {
+	case "run":
+		w.tests.get(evt.Test)
+	case "output":
+		w.tests.output(evt.Test, evt.Output)
+		if _, err := io.WriteString(w.out, evt.Output); err != nil {
+			return err
+		}
+	case "pass", "fail", "skip":
+		w.tests.finish(evt.Test, evt.Action, evt.Elapsed)
+	}
+	return nil
+}
+
+// Results returns the collected test results
+func (w *testJSONWriter) Results(exitStatus int) []*TestResult {
+	if len(w.buf) > 0 {
+		w.processLine(w.buf)
+		w.buf = nil
+	}
+	return w.tests.Results(exitStatus)
+}
+
+var (
+	testStartR  = regexp.MustCompile(`^=== (?:RUN|CONT|NAME)\s+(\S+)`)
+	testFinishR = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \(([\d.]+)s\)`)
+
+	// benchmarks print their name instead of '=== RUN' before
+	// their output, and the result line instead of '--- PASS'
+	benchStartR  = regexp.MustCompile(`^(Benchmark\S*)\n?$`)
+	benchResultR = regexp.MustCompile(`^Benchmark\S*\s+\d+\s`)
+)
+
+// parseTestOutput extracts test results from the 'go test -v' output
+// (used for backends that don't support 'go test -json')
+func parseTestOutput(events []*CompileEvent, exitStatus int) []*TestResult {
+	var output strings.Builder
+	for _, evt := range events {
+		output.WriteString(evt.Message)
+	}
+
+	tests := newTestResults()
+	current := ""
+	for _, line := range strings.SplitAfter(output.String(), "\n") {
+		if m := testStartR.FindStringSubmatch(line); m != nil {
+			current = m[1]
+			tests.get(current)
+			continue
+		}
+		if m := benchStartR.FindStringSubmatch(line); m != nil && testKind(m[1]) == "benchmark" {
+			current = m[1]
+			tests.get(current)
+		}
+		if benchResultR.MatchString(line) && testKind(current) == "benchmark" {
+			tests.output(current, line)
+			current = ""
+			continue
+		}
+		if m := testFinishR.FindStringSubmatch(line); m != nil {
+			elapsed, _ := strconv.ParseFloat(m[3], 64)
+			tests.finish(m[2], strings.ToLower(m[1]), elapsed)
+
+			// further output belongs to the parent test, if any
+			if m[2] == current {
+				current = ""
+				if i := strings.LastIndex(m[2], "/"); i != -1 {
+					current = m[2][:i]
+				}
+			}
+			continue
+		}
+		tests.output(current, line)
+	}
+
+	return tests.Results(exitStatus)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// the output of a test with subtests and an example, captured with
+// 'go test -json' (the time fields are removed) and 'go test -v'
+const (
+	testJSONOutput = `{"Action":"start"}
+{"Action":"run","Test":"TestSum"}
+{"Action":"output","Test":"TestSum","Output":"=== RUN   TestSum\n"}
+{"Action":"output","Test":"TestSum","Output":"    main_test.go:9: parent before\n"}
+{"Action":"run","Test":"TestSum/positive"}
+{"Action":"output","Test":"TestSum/positive","Output":"=== RUN   TestSum/positive\n"}
+{"Action":"output","Test":"TestSum/positive","Output":"    main_test.go:11: in positive\n"}
+{"Action":"output","Test":"TestSum/positive","Output":"--- PASS: TestSum/positive (0.00s)\n"}
+{"Action":"pass","Test":"TestSum/positive","Elapsed":0}
+{"Action":"run","Test":"TestSum/negative"}
+{"Action":"output","Test":"TestSum/negative","Output":"=== RUN   TestSum/negative\n"}
+{"Action":"output","Test":"TestSum/negative","Output":"    main_test.go:14: in negative\n"}
+{"Action":"output","Test":"TestSum/negative","Output":"--- FAIL: TestSum/negative (0.00s)\n"}
+{"Action":"fail","Test":"TestSum/negative","Elapsed":0}
+{"Action":"output","Test":"TestSum","Output":"    main_test.go:16: parent after\n"}
+{"Action":"output","Test":"TestSum","Output":"--- FAIL: TestSum (0.00s)\n"}
+{"Action":"fail","Test":"TestSum","Elapsed":0}
+{"Action":"run","Test":"Example"}
+{"Action":"output","Test":"Example","Output":"=== RUN   Example\n"}
+{"Action":"output","Test":"Example","Output":"--- PASS: Example (0.00s)\n"}
+{"Action":"pass","Test":"Example","Elapsed":0}
+{"Action":"output","Output":"FAIL\n"}
+{"Action":"fail","Elapsed":0.004}
+`
+	testVerboseOutput = `=== RUN   TestSum
+    main_test.go:9: parent before
+=== RUN   TestSum/positive
+    main_test.go:11: in positive
+=== RUN   TestSum/negative
+    main_test.go:14: in negative
+=== NAME  TestSum
+    main_test.go:16: parent after
+--- FAIL: TestSum (0.00s)
+    --- PASS: TestSum/positive (0.00s)
+    --- FAIL: TestSum/negative (0.00s)
+=== RUN   Example
+--- PASS: Example (0.00s)
+FAIL
+`
+)
+
+// the output of a benchmark (benchmarks don't report their status)
+const (
+	benchJSONOutput = `{"Action":"start"}
+{"Action":"output","Output":"goos: linux\n"}
+{"Action":"output","Output":"goarch: amd64\n"}
+{"Action":"output","Output":"pkg: play\n"}
+{"Action":"output","Output":"cpu: Intel(R) Xeon(R) Processor\n"}
+{"Action":"run","Test":"BenchmarkSum"}
+{"Action":"output","Test":"BenchmarkSum","Output":"=== RUN   BenchmarkSum\n"}
+{"Action":"output","Test":"BenchmarkSum","Output":"BenchmarkSum\n"}
+{"Action":"output","Test":"BenchmarkSum","Output":"    main_test.go:20: bench log\n"}
+{"Action":"output","Test":"BenchmarkSum","Output":"BenchmarkSum \t       1\t     51823 ns/op\n"}
+{"Action":"output","Output":"PASS\n"}
+{"Action":"pass","Elapsed":0.004}
+`
+	benchVerboseOutput = "goos: linux\n" +
+		"goarch: amd64\n" +
+		"pkg: play\n" +
+		"cpu: Intel(R) Xeon(R) Processor\n" +
+		"BenchmarkSum\n" +
+		"    main_test.go:20: bench log\n" +
+		"BenchmarkSum \t       1\t     36025 ns/op\n" +
+		"PASS\n"
+)
+
+// formatResults formats the test results for the error messages
+func formatResults(results []*TestResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&b, "%s %s %s %q\n", r.Name, r.Kind, r.Status, r.Output)
+	}
+	return b.String()
+}
+
+// testOutputResults returns the results expected for testJSONOutput
+// and testVerboseOutput with the file name in the output
+func testOutputResults(file string) []*TestResult {
+	return []*TestResult{
+		{Name: "TestSum", Kind: "test", Status: "fail", Output: "    " + file + ":9: parent before\n    " + file + ":16: parent after\n"},
+		{Name: "TestSum/positive", Kind: "test", Status: "pass", Output: "    " + file + ":11: in positive\n"},
+		{Name: "TestSum/negative", Kind: "test", Status: "fail", Output: "    " + file + ":14: in negative\n"},
+		{Name: "Example", Kind: "example", Status: "pass"},
+	}
+}
+
+// benchOutputResults returns the results expected for benchJSONOutput
+// and benchVerboseOutput
+func benchOutputResults(file, status, result string) []*TestResult {
+	return []*TestResult{
+		{Name: "BenchmarkSum", Kind: "benchmark", Status: status, Output: "BenchmarkSum\n    " + file + ":20: bench log\n" + result},
+	}
+}
+
+func TestTestJSONWriter(t *testing.T) {
+	const benchResult = "BenchmarkSum \t       1\t     51823 ns/op\n"
+	tests := []struct {
+		name       string
+		output     string
+		exitStatus int
+		results    []*TestResult
+		plain      string
+	}{
+		{
+			name:       "subtests",
+			output:     testJSONOutput,
+			exitStatus: 1,
+			results:    testOutputResults(progFile),
+			plain: "=== RUN   TestSum\n" +
+				"    main.go:9: parent before\n" +
+				"=== RUN   TestSum/positive\n" +
+				"    main.go:11: in positive\n" +
+				"--- PASS: TestSum/positive (0.00s)\n" +
+				"=== RUN   TestSum/negative\n" +
+				"    main.go:14: in negative\n" +
+				"--- FAIL: TestSum/negative (0.00s)\n" +
+				"    main.go:16: parent after\n" +
+				"--- FAIL: TestSum (0.00s)\n" +
+				"=== RUN   Example\n" +
+				"--- PASS: Example (0.00s)\n" +
+				"FAIL\n",
+		},
+		{
+			name:       "benchmark",
+			output:     benchJSONOutput,
+			exitStatus: 0,
+			results:    benchOutputResults(progFile, "pass", benchResult),
+			plain: "goos: linux\ngoarch: amd64\npkg: play\ncpu: Intel(R) Xeon(R) Processor\n" +
+				"=== RUN   BenchmarkSum\nBenchmarkSum\n    main.go:20: bench log\n" + benchResult + "PASS\n",
+		},
+		{
+			name:       "failed benchmark",
+			output:     benchJSONOutput,
+			exitStatus: 1,
+			results:    benchOutputResults(progFile, "fail", benchResult),
+			plain: "goos: linux\ngoarch: amd64\npkg: play\ncpu: Intel(R) Xeon(R) Processor\n" +
+				"=== RUN   BenchmarkSum\nBenchmarkSum\n    main.go:20: bench log\n" + benchResult + "PASS\n",
+		},
+		{
+			name:       "not a JSON record",
+			output:     "# play\nvet: main_test.go:3:1: error\n" + `{"Action":"output","Output":"ok\n"}` + "\nexit status 2",
+			exitStatus: 2,
+			plain:      "# play\nvet: main_test.go:3:1: error\nok\nexit status 2",
+		},
+	}
+	for _, test := range tests {
+		// the output comes in chunks that don't match the lines
+		for _, size := range []int{len(test.output), 7} {
+			var out strings.Builder
+			w := newTestJSONWriter(&out)
+			for s := test.output; s != ""; {
+				n := size
+				if n > len(s) {
+					n = len(s)
+				}
+				if _, err := w.Write([]byte(s[:n])); err != nil {
+					t.Fatal(err)
+				}
+				s = s[n:]
+			}
+
+			got := formatResults(w.Results(test.exitStatus))
+			if want := formatResults(test.results); got != want {
+				t.Errorf("%s, %d byte writes: got results:\n%s\nwant:\n%s", test.name, size, got, want)
+			}
+			if out.String() != test.plain {
+				t.Errorf("%s, %d byte writes: got output:\n%s\nwant:\n%s", test.name, size, out.String(), test.plain)
+			}
+		}
+	}
+}
+
+func TestParseTestOutput(t *testing.T) {
+	const benchResult = "BenchmarkSum \t       1\t     36025 ns/op\n"
+	tests := []struct {
+		name       string
+		output     string
+		exitStatus int
+		results    []*TestResult
+	}{
+		{"subtests", testVerboseOutput, 1, testOutputResults(testFile)},
+		{"benchmark", benchVerboseOutput, 0, benchOutputResults(testFile, "pass", benchResult)},
+		{"failed benchmark", benchVerboseOutput, 1, benchOutputResults(testFile, "fail", benchResult)},
+		{"no tests", "testing: warning: no tests to run\nPASS\n", 0, nil},
+	}
+	for _, test := range tests {
+		// the output is split into events at arbitrary points
+		var events []*CompileEvent
+		for s := test.output; s != ""; {
+			n := 10
+			if n > len(s) {
+				n = len(s)
+			}
+			events = append(events, &CompileEvent{Message: s[:n], Kind: "stdout"})
+			s = s[n:]
+		}
+
+		got := formatResults(parseTestOutput(events, test.exitStatus))
+		if want := formatResults(test.results); got != want {
+			t.Errorf("%s: got results:\n%s\nwant:\n%s", test.name, got, want)
+		}
+	}
+}
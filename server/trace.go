@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// traceMainFile is the generated file with the main function
+	// that runs the original one (renamed to tracedMainFunc)
+	// with the execution tracer
+	traceMainFile  = "goplayspace_trace.go"
+	tracedMainFunc = "goplayspaceTracedMain"
+
+	// traceFile is the trace written to the snippet directory
+	traceFile = "goplayspace.trace"
+
+	// maxTraceSpans is the maximum number of spans in the trace;
+	// the rest of the trace is omitted
+	maxTraceSpans = 20000
+)
+
+// traceMainTemplate is the generated main function; the imports
+// are renamed, so that they don't clash with the snippet declarations
+const traceMainTemplate = `package main
+
+import (
+	gpsos "os"
+	gpstrace "runtime/trace"
+)
+
+func main() {
+	f, err := gpsos.Create(%q)
+	if err == nil {
+		defer f.Close()
+		if err := gpstrace.Start(f); err == nil {
+			defer gpstrace.Stop()
+		}
+	}
+	%s()
+}
+`
+
+// Trace is the condensed execution trace of the program run
+type Trace struct {
+	// Duration is the trace duration in nanoseconds
+	Duration int64
+
+	// Goroutines are the goroutines of the program
+	// (the runtime ones are omitted)
+	Goroutines []*TraceGoroutine
+
+	// GC contains the garbage collection phases
+	// and stop-the-world pauses
+	GC []*TraceSpan
+
+	// Truncated is true if the trace is too long,
+	// and its end is omitted
+	Truncated bool
+}
+
+// TraceGoroutine is the timeline of a goroutine
+type TraceGoroutine struct {
+	ID int64
+
+	// Func is the function run by the goroutine (e.g. 'main.main.func1')
+	Func  string
+	Spans []*TraceSpan
+}
+
+// TraceSpan is a time range of the trace; Start and End are
+// nanoseconds since the trace start
+type TraceSpan struct {
+	Start int64
+	End   int64
+
+	// State is 'running', 'runnable', 'waiting' or 'syscall'
+	// for goroutines, and 'gc' or 'stw' for the GC spans
+	State string
+
+	// Reason describes the state, e.g. 'chan receive', 'select',
+	// 'sync' or 'sleep' for waiting goroutines
+	Reason string `json:",omitempty"`
+
+	// File and Line are the snippet source line
+	// where the goroutine was blocked
+	File string `json:",omitempty"`
+	Line int    `json:",omitempty"`
+}
+
+var errInvalidTrace = errors.New("no trace events")
+
+var (
+	// traceEventR matches the events printed by 'go tool trace -d=parsed',
+	// e.g. 'M=1 P=0 G=1 StateTransition Time=123 GoID=7 Running->Waiting Reason="chan receive"'
+	traceEventR      = regexp.MustCompile(`^M=\S+ P=\S+ G=\S+ (\w+) Time=(\d+)`)
+	traceFieldR      = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|\S+)`)
+	traceTransitionR = regexp.MustCompile(` \w+->(\w+)( |$)`)
+
+	// traceFuncR and traceLineR match the stack frame lines
+	traceFuncR = regexp.MustCompile(`^\t(\S.*) @ 0x[0-9a-f]+$`)
+	traceLineR = regexp.MustCompile(`^\t\t(.+):(\d+)$`)
+)
+
+// goroutineStates maps the trace goroutine states to TraceSpan states;
+// the other states (e.g. 'NotExist') have no spans
+var goroutineStates = map[string]string{
+	"Running":  "running",
+	"Runnable": "runnable",
+	"Waiting":  "waiting",
+	"Syscall":  "syscall",
+}
+
+// addTraceMain wraps the main function of the snippet in dir
+// with the one that runs it with the execution tracer
+func addTraceMain(dir string) error {
+	src := fmt.Sprintf(traceMainTemplate, filepath.Join(dir, traceFile), tracedMainFunc)
+	return wrapMain(dir, traceMainFile, tracedMainFunc, src)
+}
+
+// testTraceArgs returns the test binary arguments
+// that make it write the trace
+func testTraceArgs(dir string) []string {
+	return []string{"-test.trace=" + filepath.Join(dir, traceFile)}
+}
+
+// readTrace reads the trace written by the program with 'go tool trace';
+// it returns nil if there's no trace (e.g. if the program called os.Exit),
+// or if it can't be read
+func (b *localBackend) readTrace(ctx context.Context, dir string) *Trace {
+	path := filepath.Join(dir, traceFile)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := b.command(ctx, dir, "tool", "trace", "-d=parsed", path)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		log.Printf("Failed to read the trace: %v", err)
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("Failed to read the trace: %v", err)
+		return nil
+	}
+
+	trace, err := parseTrace(stdout, dir)
+	if err != nil || trace.Truncated {
+		// the rest of the output isn't read
+		cancel()
+		cmd.Wait()
+	} else {
+		err = cmd.Wait()
+	}
+	// the trace is empty if the program exited before it was flushed
+	if err != nil && err != errInvalidTrace {
+		log.Printf("Failed to read the trace: %v", err)
+	}
+	if err != nil {
+		return nil
+	}
+	return trace
+}
+
+// traceFrame is a stack frame of a trace event
+type traceFrame struct {
+	fn   string
+	file string
+	line int
+}
+
+// traceEvent is an event printed by 'go tool trace -d=parsed';
+// for state transitions, state is the new state, and transitionStack
+// is the stack of the goroutine which changed its state
+type traceEvent struct {
+	kind            string
+	time            int64
+	fields          map[string]string
+	state           string
+	transitionStack []*traceFrame
+}
+
+// traceGoroutine is the goroutine timeline being built
+type traceGoroutine struct {
+	*TraceGoroutine
+
+	// span is the current state span
+	span *TraceSpan
+
+	// system is true for the runtime goroutines
+	system bool
+}
+
+// traceParser builds the condensed trace from the events
+type traceParser struct {
+	dir        string
+	trace      *Trace
+	start, end int64
+	spans      int
+	goroutines map[int64]*traceGoroutine
+
+	// ranges are the GC spans in progress by name and scope
+	ranges map[string]*TraceSpan
+}
+
+// parseTrace condenses the output of 'go tool trace -d=parsed'
+func parseTrace(r io.Reader, dir string) (*Trace, error) {
+	p := &traceParser{
+		dir:        dir,
+		trace:      &Trace{GC: make([]*TraceSpan, 0)},
+		start:      -1,
+		goroutines: make(map[int64]*traceGoroutine),
+		ranges:     make(map[string]*TraceSpan),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var evt *traceEvent
+	var stack *[]*traceFrame
+	for scanner.Scan() && !p.trace.Truncated {
+		line := scanner.Text()
+
+		if m := traceEventR.FindStringSubmatch(line); m != nil {
+			if evt != nil {
+				p.add(evt)
+			}
+			evt = parseTraceEvent(line, m)
+			stack = nil
+			continue
+		}
+		if evt == nil {
+			continue
+		}
+
+		switch line {
+		case "TransitionStack=":
+			stack = &evt.transitionStack
+			continue
+		case "Stack=":
+			// the stack of the current goroutine isn't needed
+			stack = nil
+			continue
+		}
+		if stack == nil {
+			continue
+		}
+		if m := traceFuncR.FindStringSubmatch(line); m != nil {
+			*stack = append(*stack, &traceFrame{fn: m[1]})
+		} else if m := traceLineR.FindStringSubmatch(line); m != nil && len(*stack) > 0 {
+			frame := (*stack)[len(*stack)-1]
+			frame.file = snippetFileName(m[1], p.dir)
+			frame.line, _ = strconv.Atoi(m[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if evt != nil && !p.trace.Truncated {
+		p.add(evt)
+	}
+	if p.start == -1 {
+		return nil, errInvalidTrace
+	}
+	return p.finish(), nil
+}
+
+func parseTraceEvent(line string, m []string) *traceEvent {
+	evt := &traceEvent{kind: m[1], fields: make(map[string]string)}
+	evt.time, _ = strconv.ParseInt(m[2], 10, 64)
+	for _, f := range traceFieldR.FindAllStringSubmatch(line, -1) {
+		if v, err := strconv.Unquote(f[2]); err == nil {
+			f[2] = v
+		}
+		evt.fields[f[1]] = f[2]
+	}
+	if t := traceTransitionR.FindStringSubmatch(line); t != nil {
+		evt.state = t[1]
+	}
+	return evt
+}
+
+func (p *traceParser) add(evt *traceEvent) {
+	if p.start == -1 {
+		p.start = evt.time
+	}
+	if evt.time > p.end {
+		p.end = evt.time
+	}
+	t := evt.time - p.start
+
+	switch evt.kind {
+	case "StateTransition":
+		id, err := strconv.ParseInt(evt.fields["GoID"], 10, 64)
+		if err == nil {
+			p.transition(id, t, evt)
+		}
+	case "RangeBegin":
+		state := gcState(evt.fields["Name"])
+		if state == "" {
+			return
+		}
+		p.ranges[evt.fields["Name"]+" "+evt.fields["Scope"]] = &TraceSpan{
+			Start:  t,
+			State:  state,
+			Reason: evt.fields["Name"],
+		}
+	case "RangeEnd":
+		key := evt.fields["Name"] + " " + evt.fields["Scope"]
+		if span := p.ranges[key]; span != nil {
+			span.End = t
+			p.addSpan(&p.trace.GC, span)
+			delete(p.ranges, key)
+		}
+	}
+}
+
+// gcState returns the TraceSpan state of the GC range,
+// or an empty string for other ranges
+func gcState(name string) string {
+	switch {
+	case strings.HasPrefix(name, "stop-the-world"):
+		return "stw"
+	case strings.HasPrefix(name, "GC concurrent"):
+		return "gc"
+	}
+	return ""
+}
+
+func (p *traceParser) transition(id, t int64, evt *traceEvent) {
+	g := p.goroutines[id]
+	if g == nil {
+		g = &traceGoroutine{TraceGoroutine: &TraceGoroutine{ID: id, Spans: make([]*TraceSpan, 0)}}
+		p.goroutines[id] = g
+	}
+
+	// the outermost frame is the goroutine function
+	if stack := evt.transitionStack; g.Func == "" && len(stack) > 0 {
+		g.Func = snippetFuncName(stack[len(stack)-1].fn)
+	}
+	reason := evt.fields["Reason"]
+	if reason == "system goroutine wait" {
+		g.system = true
+	}
+
+	if g.span != nil {
+		g.span.End = t
+		p.addSpan(&g.Spans, g.span)
+		g.span = nil
+	}
+
+	state := goroutineStates[evt.state]
+	if state == "" {
+		return
+	}
+	g.span = &TraceSpan{Start: t, State: state, Reason: reason}
+	if state == "waiting" || state == "syscall" {
+		for _, frame := range evt.transitionStack {
+			if isSnippetFrame(frame) {
+				g.span.File, g.span.Line = frame.file, frame.line
+				break
+			}
+		}
+	}
+}
+
+// isSnippetFrame tells if the frame is in a snippet file
+func isSnippetFrame(frame *traceFrame) bool {
+	return frame.file != "" && !filepath.IsAbs(frame.file) && !isGeneratedFile(frame.file)
+}
+
+func (p *traceParser) addSpan(spans *[]*TraceSpan, span *TraceSpan) {
+	if p.spans >= maxTraceSpans {
+		p.trace.Truncated = true
+		return
+	}
+	p.spans++
+	*spans = append(*spans, span)
+}
+
+// finish ends the spans in progress, and returns the trace
+func (p *traceParser) finish() *Trace {
+	end := p.end - p.start
+	p.trace.Duration = end
+
+	for _, span := range p.ranges {
+		span.End = end
+		p.addSpan(&p.trace.GC, span)
+	}
+	sort.Slice(p.trace.GC, func(i, j int) bool {
+		return p.trace.GC[i].Start < p.trace.GC[j].Start
+	})
+
+	p.trace.Goroutines = make([]*TraceGoroutine, 0)
+	for _, g := range p.goroutines {
+		if g.span != nil {
+			g.span.End = end
+			p.addSpan(&g.Spans, g.span)
+		}
+		if g.system || g.Func == "" || strings.HasPrefix(g.Func, "runtime") || len(g.Spans) == 0 {
+			continue
+		}
+		p.trace.Goroutines = append(p.trace.Goroutines, g.TraceGoroutine)
+	}
+	sort.Slice(p.trace.Goroutines, func(i, j int) bool {
+		return p.trace.Goroutines[i].ID < p.trace.Goroutines[j].ID
+	})
+	return p.trace
+}
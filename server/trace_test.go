@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// traceTestOutput is an excerpt of the 'go tool trace -d=parsed' output
+// (Go 1.27) for the trace of the program in /tmp/tr/main.go:
+//
+//	ch := make(chan int)
+//	go func() {
+//		time.Sleep(time.Millisecond)
+//		ch <- 1
+//	}()
+//	<-ch
+const traceTestOutput = `M=-1 P=-1 G=-1 Sync Time=8212265210112 N=1 Trace=8212265231552 Mono=8212265231536 Wall=2026-10-17T20:08:25.034098341Z
+M=18493 P=-1 G=-1 StateTransition Time=8212265239552 ProcID=0 Undetermined->Running Reason=""
+M=18493 P=0 G=-1 StateTransition Time=8212265239936 GoID=1 Undetermined->Running Reason=""
+M=18493 P=0 G=1 Metric Time=8212265245248 Name="/sched/gomaxprocs:threads" Value=Value{Uint64(1)}
+M=18493 P=0 G=1 RangeBegin Time=8212265246464 Name="stop-the-world (start trace)" Scope=Goroutine(1)
+Stack=
+	runtime/trace.Start @ 0x4a0a49
+		/usr/local/go/src/runtime/trace/trace.go:119
+	main.main @ 0x4a0a33
+		/tmp/tr/main.go:11
+
+M=18493 P=0 G=1 RangeEnd Time=8212265255936 Name="stop-the-world (start trace)" Scope=Goroutine(1) Attributes=[]
+M=18493 P=0 G=1 StateTransition Time=8212265289216 GoID=9 NotExist->Runnable Reason=""
+TransitionStack=
+	main.main.func1 @ 0x4a0b00
+		/tmp/tr/main.go:13
+
+Stack=
+	main.main @ 0x4a0aa4
+		/tmp/tr/main.go:13
+
+M=18493 P=0 G=1 StateTransition Time=8212265290304 GoID=1 Running->Waiting Reason="chan receive"
+TransitionStack=
+	runtime.chanrecv1 @ 0x4140d1
+		/usr/local/go/src/runtime/chan.go:509
+	main.main @ 0x4a0ab0
+		/tmp/tr/main.go:17
+
+Stack=
+	runtime.chanrecv1 @ 0x4140d1
+		/usr/local/go/src/runtime/chan.go:509
+	main.main @ 0x4a0ab0
+		/tmp/tr/main.go:17
+
+M=18493 P=0 G=-1 StateTransition Time=8212265291200 GoID=9 Runnable->Running Reason=""
+M=18493 P=0 G=9 StateTransition Time=8212265292096 GoID=9 Running->Waiting Reason="sleep"
+TransitionStack=
+	time.Sleep @ 0x47d224
+		/usr/local/go/src/runtime/time.go:368
+	main.main.func1 @ 0x4a0b24
+		/tmp/tr/main.go:14
+
+Stack=
+	time.Sleep @ 0x47d224
+		/usr/local/go/src/runtime/time.go:368
+	main.main.func1 @ 0x4a0b24
+		/tmp/tr/main.go:14
+
+M=18493 P=0 G=8 StateTransition Time=8212265326720 GoID=8 Running->Waiting Reason="system goroutine wait"
+TransitionStack=
+	runtime/trace.(*traceMultiplexer).startLocked.func1 @ 0x4a06d3
+		/usr/local/go/src/runtime/trace/subscribe.go:167
+
+M=18493 P=0 G=-1 StateTransition Time=8212265327936 ProcID=0 Running->Idle Reason=""
+M=18493 P=-1 G=-1 StateTransition Time=8212266396160 ProcID=0 Idle->Running Reason=""
+M=18493 P=0 G=-1 StateTransition Time=8212266399424 GoID=9 Waiting->Runnable Reason=""
+M=18493 P=0 G=-1 StateTransition Time=8212266401152 GoID=9 Runnable->Running Reason=""
+M=18493 P=0 G=9 StateTransition Time=8212266403904 GoID=1 Waiting->Runnable Reason=""
+Stack=
+	runtime.chansend1 @ 0x413276
+		/usr/local/go/src/runtime/chan.go:161
+	main.main.func1 @ 0x4a0b35
+		/tmp/tr/main.go:15
+
+M=18493 P=0 G=9 StateTransition Time=8212266404672 GoID=9 Running->NotExist Reason=""
+M=18493 P=0 G=-1 StateTransition Time=8212266406720 GoID=1 Runnable->Running Reason=""
+M=18493 P=0 G=1 Metric Time=8212266434816 Name="/memory/classes/heap/objects:bytes" Value=Value{Uint64(2621440)}
+`
+
+func TestParseTrace(t *testing.T) {
+	got, err := parseTrace(strings.NewReader(traceTestOutput), "/tmp/tr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Trace{
+		Duration: 1224704,
+		Goroutines: []*TraceGoroutine{
+			{ID: 1, Func: "main.main", Spans: []*TraceSpan{
+				{Start: 29824, End: 80192, State: "running"},
+				{Start: 80192, End: 1193792, State: "waiting", Reason: "chan receive", File: "main.go", Line: 17},
+				{Start: 1193792, End: 1196608, State: "runnable"},
+				{Start: 1196608, End: 1224704, State: "running"},
+			}},
+			{ID: 9, Func: "main.main.func1", Spans: []*TraceSpan{
+				{Start: 79104, End: 81088, State: "runnable"},
+				{Start: 81088, End: 81984, State: "running"},
+				{Start: 81984, End: 1189312, State: "waiting", Reason: "sleep", File: "main.go", Line: 14},
+				{Start: 1189312, End: 1191040, State: "runnable"},
+				{Start: 1191040, End: 1194560, State: "running"},
+			}},
+		},
+		GC: []*TraceSpan{
+			{Start: 36352, End: 45824, State: "stw", Reason: "stop-the-world (start trace)"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, g := range got.Goroutines {
+			for _, s := range g.Spans {
+				t.Logf("%d %s: %+v", g.ID, g.Func, s)
+			}
+		}
+		for _, s := range got.GC {
+			t.Logf("GC: %+v", s)
+		}
+		t.Errorf("got the trace above, want %+v", want)
+	}
+}
+
+func TestParseTraceEmpty(t *testing.T) {
+	// e.g. the output for the trace of a program that exited
+	// before the trace was flushed, or of an unknown format
+	for _, out := range []string{"", "unknown output\n"} {
+		if _, err := parseTrace(strings.NewReader(out), "/tmp/tr"); err != errInvalidTrace {
+			t.Errorf("%q: got error %v, want %v", out, err, errInvalidTrace)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// upstreamURLs is the ordered list of upstream playground servers;
+// requests are sent to the first server that responds
+var upstreamURLs []string
+
+func parseUpstreamList(s string) []string {
+	out := make([]string, 0)
+	for _, u := range strings.Split(s, ",") {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// upstreamRequest sends the request to the upstream servers in order,
+// failing over to the next one on connection errors and 5xx responses
+func upstreamRequest(method, path, contentType string, body []byte) (int, []byte, error) {
+	return upstreamRequestTo(context.Background(), upstreamURLs, method, path, contentType, body)
+}
+
+// upstreamRequestTo is the same as upstreamRequest,
+// but uses the given list of upstream servers,
+// and stops when the context is canceled
+func upstreamRequestTo(ctx context.Context, urls []string, method, path, contentType string, body []byte) (int, []byte, error) {
+	var lastErr error
+	for _, base := range urls {
+		status, bodyBytes, err := doRequest(ctx, method, base+path, contentType, bytes.NewReader(body))
+		if err == errSnippetTooLarge {
+			return 0, nil, err
+		}
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+		if err != nil {
+			log.Printf("Upstream %s failed: %v", base, err)
+			lastErr = err
+			continue
+		}
+		if status >= http.StatusInternalServerError {
+			log.Printf("Upstream %s failed: %s %s responded with status %d", base, method, path, status)
+			lastErr = fmt.Errorf("upstream responded with status %d", status)
+			continue
+		}
+		log.Printf("%s %s served by %s", method, path, base)
+		return status, bodyBytes, nil
+	}
+	return 0, nil, lastErr
+}
+
+func upstreamPostForm(ctx context.Context, urls []string, path string, data url.Values) ([]byte, error) {
+	status, bodyBytes, err := upstreamRequestTo(ctx, urls, "POST", path, "application/x-www-form-urlencoded", []byte(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("upstream responded with status %d", status)
+	}
+	return bodyBytes, nil
+}
+
+// upstreamBackend proxies requests to play.golang.org
+// (or the servers listed in URLs, or in upstreamURLs if URLs is empty)
+type upstreamBackend struct {
+	URLs []string
+}
+
+func (b *upstreamBackend) urls() []string {
+	if len(b.URLs) > 0 {
+		return b.URLs
+	}
+	return upstreamURLs
+}
+
+// Format implements the Backend interface
+func (b *upstreamBackend) Format(ctx context.Context, body string, imports bool) (*FmtResponse, error) {
+	form := url.Values{}
+	if imports {
+		form.Add("imports", "true")
+	}
+	form.Add("body", body)
+
+	bodyBytes, err := upstreamPostForm(ctx, b.urls(), "/fmt", form)
+	if err != nil {
+		return nil, err
+	}
+
+	fmtResponse := &FmtResponse{}
+	if err := json.Unmarshal(bodyBytes, fmtResponse); err != nil {
+		return nil, err
+	}
+	return fmtResponse, nil
+}
+
+// Compile implements the Backend interface.
+// Note that play.golang.org detects the test mode automatically
+// (for programs that have test functions and no main function),
+// so opts.Test is not passed upstream. The output is only available
+// when the program has finished, so opts.OnEvent is called for all
+// the events at once.
+func (b *upstreamBackend) Compile(ctx context.Context, body string, opts *RunOptions) (*CompileResponse, error) {
+	// play.golang.org runs programs without any inputs
+	if opts.Stdin != "" || len(opts.Args) > 0 || len(opts.Env) > 0 {
+		return nil, errInputsNotSupported
+	}
+	if opts.BuildOptions.isSet() {
+		return nil, errBuildOptionsNotSupported
+	}
+	if opts.Profile {
+		return nil, errProfileNotSupported
+	}
+	if opts.Trace {
+		return nil, errTraceNotSupported
+	}
+	if opts.Fuzz != "" {
+		return nil, errFuzzNotSupported
+	}
+
+	form := url.Values{}
+	form.Add("body", body)
+	form.Add("version", "2")
+	if opts.Vet {
+		form.Add("withVet", "true")
+	}
+
+	bodyBytes, err := upstreamPostForm(ctx, b.urls(), "/compile", form)
+	if err != nil {
+		return nil, err
+	}
+
+	compileResponse := &CompileResponse{}
+	if err := json.Unmarshal(bodyBytes, compileResponse); err != nil {
+		return nil, err
+	}
+
+	if compileResponse.IsTest {
+		compileResponse.Tests = parseTestOutput(compileResponse.Events, compileResponse.Status)
+	}
+
+	if opts.OnEvent != nil {
+		for _, evt := range compileResponse.Events {
+			opts.OnEvent(evt)
+		}
+	}
+	return compileResponse, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// versions is the ordered list of Go version labels
+// (the first one is the default), and versionBackends maps
+// the labels to the backends that run the code
+var (
+	versions        []string
+	versionBackends = make(map[string]Backend)
+)
+
+var errUnknownVersion = errors.New("Unknown Go version")
+
+// VersionsResponse is the /api/v2/versions response payload
+type VersionsResponse struct {
+	Versions []string
+	Default  string
+}
+
+func addVersion(label string, b Backend) {
+	if _, ok := versionBackends[label]; !ok {
+		versions = append(versions, label)
+	}
+	versionBackends[label] = b
+}
+
+// parseVersions parses the list of versions like
+// 'go1.22=https://play.golang.org,go1.21=/usr/local/go1.21'
+// and registers the backends; targets starting with 'http://' or 'https://'
+// are upstream playground URLs (separate several URLs with '|'
+// for failover), others are local GOROOT directories
+func parseVersions(s string, timeout time.Duration) error {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tokens := strings.SplitN(entry, "=", 2)
+		label := strings.TrimSpace(tokens[0])
+		if len(tokens) != 2 || label == "" {
+			return errors.New("version must be in the 'label=target' format: " + entry)
+		}
+		target := strings.TrimSpace(tokens[1])
+
+		if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+			addVersion(label, &upstreamBackend{
+				URLs: parseUpstreamList(strings.Replace(target, "|", ",", -1)),
+			})
+			log.Printf("Go version %s: upstream %s", label, target)
+			continue
+		}
+
+		addVersion(label, &localBackend{
+			GoBin:   filepath.Join(target, "bin", "go"),
+			GOROOT:  target,
+			Timeout: timeout,
+		})
+		log.Printf("Go version %s: local GOROOT %s", label, target)
+	}
+
+	if len(versions) == 0 {
+		return errors.New("no versions provided")
+	}
+	return nil
+}
+
+// backendFor returns the backend for the Go version label,
+// or the default one if the label is empty
+func backendFor(version string) (string, Backend, error) {
+	if version == "" {
+		version = versions[0]
+	}
+	b, ok := versionBackends[version]
+	if !ok {
+		return "", nil, errUnknownVersion
+	}
+	return version, b, nil
+}
+
+// versionsHandler implements the /api/v2/versions API
+func versionsHandler(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, err := json.Marshal(&VersionsResponse{
+		Versions: versions,
+		Default:  versions[0],
+	})
+	if err != nil {
+		log.Printf("versionsResponse marshal error: %v", err)
+		http.Error(w, "Failed to encode data", http.StatusInternalServerError)
+		return
+	}
+	writeJSONBytes(w, bodyBytes)
+}
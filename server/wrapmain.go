@@ -0,0 +1,76 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// wrapMain renames the main function of the snippet in dir to fn,
+// and adds the file with the generated main function (src) that runs it;
+// if there's no main function, the snippet is left as is (the build
+// reports it). Wrapped main functions can be wrapped again.
+func wrapMain(dir, file, fn, src string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, data, 0)
+		if err != nil || f.Name.Name != "main" {
+			continue
+		}
+		for _, decl := range f.Decls {
+			d, ok := decl.(*ast.FuncDecl)
+			if !ok || d.Recv != nil || d.Name.Name != "main" {
+				continue
+			}
+
+			// the name is replaced in place, so that
+			// line numbers stay the same
+			off := fset.Position(d.Name.Pos()).Offset
+			renamed := string(data[:off]) + fn + string(data[off+len("main"):])
+			if err := ioutil.WriteFile(path, []byte(renamed), 0644); err != nil {
+				return err
+			}
+			return ioutil.WriteFile(filepath.Join(dir, file), []byte(src), 0644)
+		}
+	}
+	return nil
+}
+
+// isGeneratedFile tells if the file is one of the generated
+// main function files (including the one generated by 'go test')
+func isGeneratedFile(file string) bool {
+	return file == profileMainFile || file == traceMainFile || file == "_testmain.go"
+}
+
+// snippetFuncName returns the function name as it is in the snippet,
+// i.e. 'main.main' for the wrapped main function
+func snippetFuncName(name string) string {
+	name = strings.Replace(name, profiledMainFunc, "main", 1)
+	return strings.Replace(name, tracedMainFunc, "main", 1)
+}
+
+// snippetFileName returns the file name relative to the snippet
+// directory for snippet files, and the absolute one for other files
+func snippetFileName(file, dir string) string {
+	file = strings.TrimPrefix(filepath.ToSlash(file), filepath.ToSlash(dir)+"/")
+	if file == testFile {
+		return progFile
+	}
+	return file
+}
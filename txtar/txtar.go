@@ -0,0 +1,152 @@
+// Package txtar implements the trivial text-based file archive format
+// used by play.golang.org for multi-file snippets.
+// It mirrors the golang.org/x/tools/txtar package.
+//
+// An archive is a comment, followed by a sequence of files.
+// Each file starts with a '-- name --' marker line:
+//
+//	comment text
+//	-- go.mod --
+//	module play
+//	-- util/util.go --
+//	package util
+//
+// The playground treats the comment as the main program file.
+package txtar
+
+import (
+	"bytes"
+	"strings"
+)
+
+// MainFile is the name given to the comment section of the archive
+// (the implicit main program file)
+const MainFile = "main.go"
+
+// Archive is a collection of files
+type Archive struct {
+	Comment []byte
+	Files   []File
+}
+
+// File is a single file in an archive
+type File struct {
+	Name string
+	Data []byte
+}
+
+var (
+	newlineMarker = []byte("\n-- ")
+	marker        = []byte("-- ")
+	markerEnd     = []byte(" --")
+)
+
+// Format returns the serialized form of an Archive.
+// It is assumed that the Archive data structure is well-formed:
+// a.Comment and all a.File[i].Data contain no file marker lines,
+// and all a.File[i].Name is non-empty.
+func Format(a *Archive) []byte {
+	var buf bytes.Buffer
+	buf.Write(fixNL(a.Comment))
+	for _, f := range a.Files {
+		buf.WriteString("-- " + f.Name + " --\n")
+		buf.Write(fixNL(f.Data))
+	}
+	return buf.Bytes()
+}
+
+// Parse parses the serialized form of an Archive.
+// The returned Archive holds slices of data.
+func Parse(data []byte) *Archive {
+	a := new(Archive)
+	var name string
+	a.Comment, name, data = findFileMarker(data)
+	for name != "" {
+		f := File{name, nil}
+		f.Data, name, data = findFileMarker(data)
+		a.Files = append(a.Files, f)
+	}
+	return a
+}
+
+// IsArchive returns true if the data contains at least one file marker
+func IsArchive(data []byte) bool {
+	_, name, _ := findFileMarker(data)
+	return name != ""
+}
+
+// findFileMarker finds the next file marker in data,
+// extracts the file name, and returns the data before the marker,
+// the file name, and the data after the marker.
+// If there is no next marker, findFileMarker returns before = fixNL(data), name = "", after = nil.
+func findFileMarker(data []byte) (before []byte, name string, after []byte) {
+	var i int
+	for {
+		if name, after = isMarker(data[i:]); name != "" {
+			return data[:i], name, after
+		}
+		j := bytes.Index(data[i:], newlineMarker)
+		if j < 0 {
+			return fixNL(data), "", nil
+		}
+		i += j + 1 // positioned at start of new possible marker
+	}
+}
+
+// isMarker checks whether data begins with a file marker line.
+// If so, it returns the name from the line and the data after the line.
+// Otherwise it returns name == "" with an unspecified after.
+func isMarker(data []byte) (name string, after []byte) {
+	if !bytes.HasPrefix(data, marker) {
+		return "", nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data, after = data[:i], data[i+1:]
+	}
+	if !bytes.HasSuffix(data, markerEnd) || len(data) < len(marker)+len(markerEnd) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data[len(marker) : len(data)-len(markerEnd)])), after
+}
+
+// fixNL returns data with a final newline,
+// adding one if needed
+func fixNL(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+	d := make([]byte, len(data)+1)
+	copy(d, data)
+	d[len(data)] = '\n'
+	return d
+}
+
+// Split returns the list of snippet files, with the comment section
+// (if it is not blank) turned into the MainFile
+func Split(data []byte) []File {
+	if !IsArchive(data) {
+		return []File{{MainFile, data}}
+	}
+	a := Parse(data)
+	files := make([]File, 0, len(a.Files)+1)
+	if len(bytes.TrimSpace(a.Comment)) > 0 || len(a.Files) == 0 {
+		files = append(files, File{MainFile, a.Comment})
+	}
+	return append(files, a.Files...)
+}
+
+// Join is the reverse of Split: it returns the contents of MainFile
+// as is if it is the only file, or the archive with MainFile
+// (if it goes first) stored as the comment section
+func Join(files []File) []byte {
+	if len(files) == 1 && files[0].Name == MainFile {
+		return files[0].Data
+	}
+	a := &Archive{}
+	if len(files) > 0 && files[0].Name == MainFile {
+		a.Comment = files[0].Data
+		files = files[1:]
+	}
+	a.Files = files
+	return Format(a)
+}
@@ -0,0 +1,204 @@
+package txtar
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var parseTests = []struct {
+	name   string
+	text   string
+	parsed *Archive
+}{
+	{
+		name: "basic",
+		text: `comment1
+comment2
+-- file1 --
+File 1 text.
+-- foo ---
+More file 1 text.
+-- file 2 --
+File 2 text.
+-- empty --
+-- noNL --
+hello world
+-- empty filename line --
+some content
+-- --`,
+		parsed: &Archive{
+			Comment: []byte("comment1\ncomment2\n"),
+			Files: []File{
+				{"file1", []byte("File 1 text.\n-- foo ---\nMore file 1 text.\n")},
+				{"file 2", []byte("File 2 text.\n")},
+				{"empty", []byte{}},
+				{"noNL", []byte("hello world\n")},
+				{"empty filename line", []byte("some content\n-- --\n")},
+			},
+		},
+	},
+	{
+		name: "marker without a newline",
+		text: "comment\n-- a --",
+		parsed: &Archive{
+			Comment: []byte("comment\n"),
+			Files:   []File{{"a", nil}},
+		},
+	},
+	{
+		name: "no comment",
+		text: "-- a --\nA\n-- b --\nB\n",
+		parsed: &Archive{
+			Comment: []byte{},
+			Files:   []File{{"a", []byte("A\n")}, {"b", []byte("B\n")}},
+		},
+	},
+	{
+		name: "no files",
+		text: "just a comment",
+		parsed: &Archive{
+			Comment: []byte("just a comment\n"),
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range parseTests {
+		a := Parse([]byte(test.text))
+		if !reflect.DeepEqual(a, test.parsed) {
+			t.Errorf("%s: wrong archive:\nhave:\n%s\nwant:\n%s", test.name, shortArchive(a), shortArchive(test.parsed))
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  *Archive
+		wanted string
+	}{
+		{
+			name: "basic",
+			input: &Archive{
+				Comment: []byte("comment1\ncomment2\n"),
+				Files: []File{
+					{"file1", []byte("File 1 text.\n-- foo ---\nMore file 1 text.\n")},
+					{"file 2", []byte("File 2 text.\n")},
+					{"empty", []byte{}},
+					{"noNL", []byte("hello world")},
+				},
+			},
+			wanted: `comment1
+comment2
+-- file1 --
+File 1 text.
+-- foo ---
+More file 1 text.
+-- file 2 --
+File 2 text.
+-- empty --
+-- noNL --
+hello world
+`,
+		},
+		{
+			name: "comment without a newline",
+			input: &Archive{
+				Comment: []byte("comment"),
+			},
+			wanted: "comment\n",
+		},
+	}
+	for _, test := range tests {
+		result := Format(test.input)
+		if string(result) != test.wanted {
+			t.Errorf("%s: wrong output:\nhave:\n%s\nwant:\n%s", test.name, result, test.wanted)
+		}
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"package main\n", false},
+		{"package main\n\n// -- not a marker --\n", false},
+		{"-- go.mod --\nmodule play\n", true},
+		{"package main\n-- go.mod --\nmodule play\n", true},
+		{"package main\n-- --\n", false},
+	}
+	for _, test := range tests {
+		if got := IsArchive([]byte(test.text)); got != test.want {
+			t.Errorf("IsArchive(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		files []File
+	}{
+		{
+			name:  "single file",
+			text:  "package main\n",
+			files: []File{{MainFile, []byte("package main\n")}},
+		},
+		{
+			name: "comment as the main file",
+			text: "package main\n-- go.mod --\nmodule play\n",
+			files: []File{
+				{MainFile, []byte("package main\n")},
+				{"go.mod", []byte("module play\n")},
+			},
+		},
+		{
+			name: "blank comment",
+			text: "\n\n-- main.go --\npackage main\n-- go.mod --\nmodule play\n",
+			files: []File{
+				{MainFile, []byte("package main\n")},
+				{"go.mod", []byte("module play\n")},
+			},
+		},
+	}
+	for _, test := range tests {
+		files := Split([]byte(test.text))
+		if !reflect.DeepEqual(files, test.files) {
+			t.Errorf("%s: got %q, want %q", test.name, files, test.files)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []string{
+		"package main\n",
+		"package main",
+		"package main\n-- go.mod --\nmodule play\n",
+		"-- a.go --\npackage a\n-- b.go --\npackage b\n",
+	}
+	for _, text := range tests {
+		if got := Join(Split([]byte(text))); string(got) != text {
+			t.Errorf("Join(Split(%q)) = %q, want it unchanged", text, got)
+		}
+	}
+
+	// the main file goes to the comment only if it is the first one
+	files := []File{{"go.mod", []byte("module play\n")}, {MainFile, []byte("package main\n")}}
+	want := "-- go.mod --\nmodule play\n-- main.go --\npackage main\n"
+	if got := Join(files); string(got) != want {
+		t.Errorf("Join(%q) = %q, want %q", files, got, want)
+	}
+}
+
+func shortArchive(a *Archive) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "comment: %q\n", a.Comment)
+	for _, f := range a.Files {
+		fmt.Fprintf(&buf, "file %q: %q\n", f.Name, f.Data)
+	}
+	return buf.String()
+}